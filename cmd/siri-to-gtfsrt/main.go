@@ -10,12 +10,14 @@ import (
 	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
 	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/formatter"
 	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
 )
 
 func main() {
 	input := flag.String("input", "stdin", "file|url|stdin (url not yet supported)")
 	path := flag.String("path", "", "PATH or URL when input is file or url")
-	outfmt := flag.String("out", "gtfsrt-pbf", "gtfsrt-pbf|gtfsrt-json")
+	infmt := flag.String("in", "siri-xml", "siri-xml|siri-json")
+	outfmt := flag.String("out", "gtfsrt-pbf", "gtfsrt-pbf|gtfsrt-json|gtfsrt-protojson")
 	kind := flag.String("type", "all", "trip-updates|vehicle-positions|alerts|all")
 	output := flag.String("output", "", "output file or directory (stdout if empty)")
 	split := flag.Bool("split", false, "when --type=all and output is a directory, write separate files")
@@ -38,9 +40,17 @@ func main() {
 		log.Fatalf("unsupported input: %s", *input)
 	}
 
-	sd, err := formatter.DecodeSIRI(f)
+	var sd *siri.ServiceDelivery
+	switch *infmt {
+	case "siri-xml":
+		sd, err = formatter.DecodeSIRI(f)
+	case "siri-json":
+		sd, err = formatter.DecodeSIRIJSON(f)
+	default:
+		log.Fatalf("unsupported --in: %s", *infmt)
+	}
 	if err != nil {
-		log.Fatalf("decode xml: %v", err)
+		log.Fatalf("decode %s: %v", *infmt, err)
 	}
 
 	entities, err := converter.ConvertSIRI(sd, converter.DefaultOptions())
@@ -67,7 +77,8 @@ func main() {
 	}
 
 	switch *outfmt {
-	case "gtfsrt-json":
+	case "gtfsrt-json", "gtfsrt-protojson":
+		protoJSON := *outfmt == "gtfsrt-protojson"
 		if *output == "" {
 			first := true
 			for name, m := range msgs {
@@ -76,11 +87,11 @@ func main() {
 				}
 				first = false
 				fmt.Printf("# %s\n", name)
-				enc := json.NewEncoder(os.Stdout)
-				enc.SetIndent("", "  ")
-				if err := enc.Encode(m); err != nil {
+				b, err := marshalJSON(m, protoJSON)
+				if err != nil {
 					log.Fatalf("encode json: %v", err)
 				}
+				fmt.Println(string(b))
 			}
 		} else {
 			fi, err := os.Stat(*output)
@@ -90,19 +101,26 @@ func main() {
 						continue
 					}
 					p := *output + "/" + name + ".json"
-					if err := writeJSON(p, m); err != nil {
+					if err := writeJSON(p, m, protoJSON); err != nil {
 						log.Fatalf("write %s: %v", p, err)
 					}
 				}
 			} else {
 				if len(msgs) == 1 {
 					for _, m := range msgs {
-						if err := writeJSON(*output, m); err != nil {
+						if err := writeJSON(*output, m, protoJSON); err != nil {
 							log.Fatalf("write %s: %v", *output, err)
 						}
 					}
 				} else {
-					out := map[string]*gtfsrt.FeedMessage(msgs)
+					out := make(map[string]json.RawMessage, len(msgs))
+					for name, m := range msgs {
+						b, err := marshalJSON(m, protoJSON)
+						if err != nil {
+							log.Fatalf("encode json: %v", err)
+						}
+						out[name] = b
+					}
 					b, err := json.MarshalIndent(out, "", "  ")
 					if err != nil {
 						log.Fatalf("encode json: %v", err)
@@ -162,14 +180,23 @@ func filterByKind(entities []converter.Entity, kind string) []converter.Entity {
 	return out
 }
 
-func writeJSON(path string, m *gtfsrt.FeedMessage) error {
-	b, err := json.MarshalIndent(m, "", "  ")
+func writeJSON(path string, m *gtfsrt.FeedMessage, protoJSON bool) error {
+	b, err := marshalJSON(m, protoJSON)
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(path, b, 0o644)
 }
 
+// marshalJSON renders the internal JSON view, or with protoJSON the protojson
+// rendering of the GTFS-RT protobuf message.
+func marshalJSON(m *gtfsrt.FeedMessage, protoJSON bool) ([]byte, error) {
+	if protoJSON {
+		return gtfsrt.MarshalProtoJSON(gtfsrt.ToProto(m))
+	}
+	return json.MarshalIndent(m, "", "  ")
+}
+
 func writePBF(path string, m *gtfsrt.FeedMessage) error {
 	b, err := gtfsrt.MarshalPBF(m)
 	if err != nil {
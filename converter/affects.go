@@ -0,0 +1,441 @@
+package converter
+
+import (
+	"strconv"
+	"strings"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// SX Affects -> informed_entity
+
+// alertAffects returns the Affects used for informed entities: the union of
+// all PublishAtScope affects when Options.SXUsePublishAtScope is set and the
+// situation has any, otherwise the top-level Affects.
+func alertAffects(sx *siri.PtSituationElement, opts Options) *siri.Affects {
+	if opts.SXUsePublishAtScope {
+		var scoped siri.Affects
+		found := false
+		for _, pa := range sx.PublishingActions {
+			if pa.PublishAtScope == nil || pa.PublishAtScope.Affects == nil {
+				continue
+			}
+			a := pa.PublishAtScope.Affects
+			scoped.Operators = append(scoped.Operators, a.Operators...)
+			scoped.StopPoints = append(scoped.StopPoints, a.StopPoints...)
+			scoped.VehicleJourneys = append(scoped.VehicleJourneys, a.VehicleJourneys...)
+			scoped.Networks = append(scoped.Networks, a.Networks...)
+			scoped.StopPlaces = append(scoped.StopPlaces, a.StopPlaces...)
+			found = true
+		}
+		if found {
+			return &scoped
+		}
+	}
+	return sx.Affects
+}
+
+// situationDate is the start date of the first parseable ValidityPeriod, used
+// as the last-resort start_date of affected journeys.
+func situationDate(sx *siri.PtSituationElement) string {
+	for _, vp := range sx.ValidityPeriods {
+		if vp.StartTime == nil {
+			continue
+		}
+		if t, ok := siri.ParseISOTime(*vp.StartTime); ok {
+			return siri.FormatDateYYYYMMDD(t)
+		}
+	}
+	return ""
+}
+
+// mapInformedEntities maps the affects to selectors; fallbackDate is the
+// start_date used for framed journeys whose DataFrameRef holds no valid date
+// and that carry no OriginAimedDepartureTime.
+func mapInformedEntities(a *siri.Affects, fallbackDate string, opts Options) []*gtfs.EntitySelector {
+	var out []*gtfs.EntitySelector
+	for _, op := range a.Operators {
+		if op.OperatorRef != nil {
+			aid := stripPrefix(*op.OperatorRef, "SOFIA:Operator:")
+			out = append(out, &gtfs.EntitySelector{AgencyId: &aid})
+		}
+	}
+	for _, sp := range a.StopPoints {
+		if sp.StopPointRef != nil {
+			sid := stripPrefix(*sp.StopPointRef, "SOFIA:Quay:")
+			out = append(out, &gtfs.EntitySelector{StopId: &sid})
+		}
+	}
+	for _, vj := range a.VehicleJourneys {
+		out = append(out, vehicleJourneySelectors(vj, fallbackDate)...)
+	}
+	for _, net := range a.Networks {
+		if len(net.AffectedLines) == 0 {
+			out = append(out, networkSelectors(net, opts)...)
+		}
+		for _, line := range net.AffectedLines {
+			out = append(out, lineSelectors(line)...)
+		}
+	}
+	for _, sp := range a.StopPlaces {
+		out = append(out, stopPlaceSelectors(sp, opts)...)
+	}
+	return dedupeSelectors(out)
+}
+
+// vehicleJourneySelectors combines the journey's trips with its affected
+// stops, keeping the route on every selector. Without trip references, stops
+// are scoped to the line and direction; the bare line is only informed when
+// neither trips nor stops are given. The GTFS route is the SIRI LineRef; a
+// RouteRef names a journey pattern and is not used as route_id.
+func vehicleJourneySelectors(vj siri.AffectedVehicleJourney, fallbackDate string) []*gtfs.EntitySelector {
+	var routeID *string
+	if vj.LineRef != nil {
+		rid := stripPrefix(*vj.LineRef, "SOFIA:Line:")
+		routeID = &rid
+	}
+
+	var originDate string
+	if vj.OriginAimedDepartureTime != nil {
+		if t, ok := siri.ParseISOTime(*vj.OriginAimedDepartureTime); ok {
+			originDate = siri.FormatDateYYYYMMDD(t)
+		}
+	}
+
+	var trips []*gtfs.TripDescriptor
+	if vj.FramedVehicleJourneyRef != nil && vj.FramedVehicleJourneyRef.DatedVehicleJourneyRef != nil {
+		tid := stripPrefix(*vj.FramedVehicleJourneyRef.DatedVehicleJourneyRef, "SOFIA:ServiceJourney:")
+		td := &gtfs.TripDescriptor{TripId: &tid}
+		if d, ok := sanitizeDate(derefString(vj.FramedVehicleJourneyRef.DataFrameRef)); ok {
+			td.StartDate = &d
+		} else if originDate != "" {
+			td.StartDate = &originDate
+		} else {
+			td.StartDate = strPtrOrNil(fallbackDate)
+		}
+		trips = append(trips, td)
+	}
+	for _, dvj := range vj.DatedVehicleJourneyRefs {
+		tid := stripPrefix(dvj, "SOFIA:ServiceJourney:")
+		trips = append(trips, &gtfs.TripDescriptor{TripId: &tid, StartDate: strPtrOrNil(originDate)})
+	}
+
+	var stops []string
+	for _, r := range vj.Routes {
+		stops = append(stops, routeStops(r)...)
+	}
+
+	var out []*gtfs.EntitySelector
+	switch {
+	case len(trips) > 0 && len(stops) > 0:
+		for i := range trips {
+			for _, sid := range stops {
+				sid := sid
+				out = append(out, &gtfs.EntitySelector{RouteId: routeID, Trip: trips[i], StopId: &sid})
+			}
+		}
+	case len(trips) > 0:
+		for i := range trips {
+			out = append(out, &gtfs.EntitySelector{RouteId: routeID, Trip: trips[i]})
+		}
+	default:
+		dir, hasDir := directionID(derefString(vj.DirectionRef))
+		base := &gtfs.EntitySelector{RouteId: routeID}
+		if hasDir && base.RouteId != nil {
+			base.DirectionId = &dir
+		}
+		out = append(out, withStops(base, stops)...)
+	}
+	return out
+}
+
+// lineSelectors scopes the line's affected stops (from routes and sections)
+// to the line and direction; a line without stops is informed per direction.
+func lineSelectors(line siri.AffectedLine) []*gtfs.EntitySelector {
+	base := &gtfs.EntitySelector{}
+	if line.LineRef != nil {
+		rid := stripPrefix(*line.LineRef, "SOFIA:Line:")
+		base.RouteId = &rid
+	}
+	lineDirs := directionIDs(line.Directions)
+
+	var out []*gtfs.EntitySelector
+	var lineStops []string
+	for _, sec := range line.Sections {
+		lineStops = append(lineStops, sectionStops(sec)...)
+	}
+	for _, r := range line.Routes {
+		dirs := directionIDs(r.Directions)
+		if len(dirs) == 0 {
+			dirs = lineDirs
+		}
+		out = append(out, withDirections(base, dirs, routeStops(r))...)
+	}
+	if len(lineStops) > 0 || len(out) == 0 {
+		out = append(out, withDirections(base, lineDirs, lineStops)...)
+	}
+	return out
+}
+
+func withDirections(base *gtfs.EntitySelector, dirs []uint32, stops []string) []*gtfs.EntitySelector {
+	if len(dirs) == 0 || base.RouteId == nil {
+		return withStops(base, stops)
+	}
+	var out []*gtfs.EntitySelector
+	for _, d := range dirs {
+		sel := cloneSelector(base)
+		d := d
+		sel.DirectionId = &d
+		out = append(out, withStops(sel, stops)...)
+	}
+	return out
+}
+
+// withStops returns base once per stop, or base alone when there are no stops.
+func withStops(base *gtfs.EntitySelector, stops []string) []*gtfs.EntitySelector {
+	if len(stops) == 0 {
+		if base.RouteId == nil {
+			return nil
+		}
+		return []*gtfs.EntitySelector{cloneSelector(base)}
+	}
+	out := make([]*gtfs.EntitySelector, 0, len(stops))
+	for _, sid := range stops {
+		sel := cloneSelector(base)
+		sid := sid
+		sel.StopId = &sid
+		out = append(out, sel)
+	}
+	return out
+}
+
+func routeStops(r siri.AffectedRoute) []string {
+	var stops []string
+	for _, sp := range r.StopPoints.StopPoints {
+		if sp.StopPointRef != nil {
+			stops = append(stops, stripPrefix(*sp.StopPointRef, "SOFIA:Quay:"))
+		}
+	}
+	for _, sec := range r.Sections {
+		stops = append(stops, sectionStops(sec)...)
+	}
+	return stops
+}
+
+// sectionStops returns the boundary quays of an indirectly referenced section.
+func sectionStops(sec siri.AffectedSection) []string {
+	if sec.IndirectSectionRef == nil {
+		return nil
+	}
+	var stops []string
+	for _, q := range []*string{sec.IndirectSectionRef.FirstQuayRef, sec.IndirectSectionRef.LastQuayRef} {
+		if q != nil && *q != "" {
+			stops = append(stops, stripPrefix(*q, "SOFIA:Quay:"))
+		}
+	}
+	return stops
+}
+
+func directionIDs(dirs []siri.AffectedDirection) []uint32 {
+	var out []uint32
+	for _, d := range dirs {
+		if id, ok := directionID(derefString(d.DirectionRef)); ok {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// directionID maps SIRI DirectionRef values to GTFS direction_id
+// (0 = outbound, 1 = inbound).
+func directionID(ref string) (uint32, bool) {
+	switch strings.ToLower(strings.TrimSpace(ref)) {
+	case "0", "outbound", "clockwise", "a":
+		return 0, true
+	case "1", "inbound", "anticlockwise", "counterclockwise", "b":
+		return 1, true
+	default:
+		return 0, false
+	}
+}
+
+// dedupeSelectors drops selectors identical in every field, keeping order.
+func dedupeSelectors(in []*gtfs.EntitySelector) []*gtfs.EntitySelector {
+	seen := make(map[string]bool, len(in))
+	out := in[:0]
+	for _, sel := range in {
+		k := selectorKey(sel)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, sel)
+	}
+	return out
+}
+
+func cloneSelector(sel *gtfs.EntitySelector) *gtfs.EntitySelector {
+	return proto.Clone(sel).(*gtfs.EntitySelector)
+}
+
+func selectorKey(sel *gtfs.EntitySelector) string {
+	var b strings.Builder
+	b.WriteString(derefString(sel.AgencyId))
+	b.WriteByte('|')
+	if sel.RouteType != nil {
+		b.WriteString(strconv.Itoa(int(*sel.RouteType)))
+	}
+	b.WriteByte('|')
+	b.WriteString(derefString(sel.RouteId))
+	b.WriteByte('|')
+	if sel.DirectionId != nil {
+		b.WriteString(strconv.Itoa(int(*sel.DirectionId)))
+	}
+	b.WriteByte('|')
+	b.WriteString(derefString(sel.StopId))
+	b.WriteByte('|')
+	if td := sel.Trip; td != nil {
+		b.WriteString(td.GetTripId() + "/" + td.GetRouteId() + "/" + td.GetStartDate() + "/" + td.GetStartTime())
+	}
+	return b.String()
+}
+
+// networkSelectors handles networks affected as a whole (AllLines or no
+// AffectedLine): operators become agency_id selectors, VehicleMode becomes
+// route_type, and a network-wide situation without either falls back to
+// Options.NetworkAgencyID.
+func networkSelectors(net siri.AffectedNetwork, opts Options) []*gtfs.EntitySelector {
+	var out []*gtfs.EntitySelector
+	routeType, hasMode := routeTypeFromVehicleMode(derefString(net.VehicleMode))
+	for _, op := range net.AffectedOperators {
+		if op.OperatorRef == nil {
+			continue
+		}
+		aid := stripPrefix(*op.OperatorRef, "SOFIA:Operator:")
+		sel := &gtfs.EntitySelector{AgencyId: &aid}
+		if hasMode {
+			rt := routeType
+			sel.RouteType = &rt
+		}
+		out = append(out, sel)
+	}
+	if len(out) > 0 {
+		return out
+	}
+	if hasMode {
+		return []*gtfs.EntitySelector{{RouteType: &routeType}}
+	}
+	if net.AllLines != nil && opts.NetworkAgencyID != "" {
+		aid := opts.NetworkAgencyID
+		return []*gtfs.EntitySelector{{AgencyId: &aid}}
+	}
+	return nil
+}
+
+// routeTypeFromVehicleMode maps SIRI VehicleMode values to basic GTFS route types.
+func routeTypeFromVehicleMode(mode string) (int32, bool) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "tram", "lightrail":
+		return 0, true
+	case "metro", "underground":
+		return 1, true
+	case "rail", "intercityrail", "urbanrail", "suburbanrail":
+		return 2, true
+	case "bus", "coach":
+		return 3, true
+	case "ferry", "water":
+		return 4, true
+	case "cableway", "telecabin":
+		return 6, true
+	case "funicular":
+		return 7, true
+	case "trolleybus":
+		return 11, true
+	case "monorail":
+		return 12, true
+	default:
+		return 0, false
+	}
+}
+
+// stopPlaceSelectors informs the station itself and, when a static GTFS stop
+// index is available, its child stops and its own parent station (for stop
+// places that GTFS models below a station). Affected components such as
+// entrances and lifts are informed through componentSelectors.
+func stopPlaceSelectors(sp siri.AffectedStopPlace, opts Options) []*gtfs.EntitySelector {
+	var out []*gtfs.EntitySelector
+	if sp.StopPlaceRef != nil {
+		station := stripPrefix(*sp.StopPlaceRef, "SOFIA:StopPlace:")
+		out = append(out, &gtfs.EntitySelector{StopId: &station})
+		if parent := opts.Stops.Parent(station); parent != "" {
+			out = append(out, &gtfs.EntitySelector{StopId: &parent})
+		}
+		for _, child := range opts.Stops.Children(station) {
+			cid := child
+			out = append(out, &gtfs.EntitySelector{StopId: &cid})
+		}
+	}
+	for _, c := range sp.AffectedComponents {
+		if c.ComponentRef == nil {
+			continue
+		}
+		// GTFS models entrances as stops with location_type=2
+		entrance := strings.EqualFold(derefString(c.ComponentType), "entrance")
+		out = append(out, componentSelectors(*c.ComponentRef, entrance, opts)...)
+	}
+	return out
+}
+
+// componentSelectors informs a stop place component that static GTFS models
+// as a stop of its own (entrance, generic node or boarding area) together
+// with its parent station from the stop index. Entrances are informed even
+// without an index; other components only when the index knows them.
+func componentSelectors(ref string, entrance bool, opts Options) []*gtfs.EntitySelector {
+	id := stripPrefix(stripPrefix(ref, "SOFIA:StopPlaceEntrance:"), "SOFIA:StopPlaceComponent:")
+	parent := opts.Stops.Parent(id)
+	if id == "" || (!entrance && parent == "") {
+		return nil
+	}
+	out := []*gtfs.EntitySelector{{StopId: &id}}
+	if parent != "" {
+		out = append(out, &gtfs.EntitySelector{StopId: &parent})
+	}
+	return out
+}
+
+// hasAccessibilityIssue reports whether any affected stop place declares
+// reduced accessibility or an affected lift, escalator or ramp.
+func hasAccessibilityIssue(a *siri.Affects) bool {
+	for _, sp := range a.StopPlaces {
+		if reducedAccessibility(sp.AccessibilityAssessment) {
+			return true
+		}
+		for _, c := range sp.AffectedComponents {
+			switch strings.ToLower(derefString(c.AccessFeatureType)) {
+			case "lift", "elevator", "escalator", "travelator", "ramp":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func reducedAccessibility(aa *siri.AccessibilityAssessment) bool {
+	if aa == nil {
+		return false
+	}
+	if aa.MobilityImpairedAccess != nil && !*aa.MobilityImpairedAccess {
+		return true
+	}
+	for _, l := range aa.Limitations {
+		for _, v := range []*string{l.WheelchairAccess, l.StepFreeAccess, l.EscalatorFreeAccess, l.LiftFreeAccess} {
+			if v != nil && strings.EqualFold(strings.TrimSpace(*v), "false") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package converter
+
+import (
+	"path"
+	"strings"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// SX texts, images and details
+
+// mapAlertTexts fills the optional text fields of an alert from Detail,
+// Advice, Consequences, Images, ReasonName and InfoLink labels.
+func mapAlertTexts(sx *siri.PtSituationElement, alert *gtfs.Alert, opts Options) {
+	if pia := passengerInformation(sx); opts.SXUsePassengerInformation && pia != nil {
+		applyPassengerInformation(pia, alert)
+	} else if opts.SXComposeDescription {
+		if d := composeDescription(sx); d != nil {
+			alert.DescriptionText = d
+		}
+	}
+	if opts.SXTextToSpeech {
+		alert.TtsHeaderText = copyTranslatedString(alert.HeaderText)
+		alert.TtsDescriptionText = copyTranslatedString(alert.DescriptionText)
+	}
+
+	var image gtfsrt.TranslatedImage
+	for _, img := range sx.Images {
+		if img.ImageRef == nil || strings.TrimSpace(*img.ImageRef) == "" {
+			continue
+		}
+		u := strings.TrimSpace(*img.ImageRef)
+		image.LocalizedImage = append(image.LocalizedImage, gtfsrt.LocalizedImage{Url: u, MediaType: imageMediaType(u)})
+	}
+	if len(image.LocalizedImage) > 0 {
+		gtfsrt.SetAlertImage(alert, &image)
+	}
+
+	if bg, en := langTexts(sx.ReasonNames); bg != "" || en != "" {
+		gtfsrt.SetAlertCauseDetail(alert, bgEnString(bg, en))
+	}
+	var bgConds, enConds []string
+	seen := make(map[string]bool)
+	for _, c := range sx.Consequences {
+		for _, cond := range c.Conditions {
+			key := strings.ToLower(strings.TrimSpace(cond))
+			t, ok := conditionTexts[key]
+			if !ok || seen[key] {
+				continue
+			}
+			seen[key] = true
+			bgConds = append(bgConds, t[0])
+			enConds = append(enConds, t[1])
+		}
+	}
+	if len(enConds) > 0 {
+		gtfsrt.SetAlertEffectDetail(alert, bgEnString(strings.Join(bgConds, ", "), strings.Join(enConds, ", ")))
+	}
+}
+
+// conditionTexts holds the bg and en rider texts of SIRI ServiceCondition
+// values; other conditions are not shown in effect_detail.
+var conditionTexts = map[string][2]string{
+	"cancelled":            {"Отменен", "Cancelled"},
+	"noservice":            {"Няма обслужване", "No service"},
+	"delayed":              {"Закъснения", "Delays"},
+	"diverted":             {"Променен маршрут", "Diverted"},
+	"disrupted":            {"Нарушено движение", "Disrupted service"},
+	"altered":              {"Променено разписание", "Altered service"},
+	"intermittentservice":  {"Нередовно обслужване", "Intermittent service"},
+	"shortformedservice":   {"Съкратен състав", "Short-formed service"},
+	"additionalservice":    {"Допълнителни курсове", "Additional service"},
+	"specialservice":       {"Специално обслужване", "Special service"},
+	"replacementtransport": {"Заместващ транспорт", "Replacement transport"},
+	"replacementservice":   {"Заместващ транспорт", "Replacement transport"},
+	"shuttleservice":       {"Совалка", "Shuttle service"},
+	"arrivesearly":         {"Пристига по-рано", "Arrives early"},
+}
+
+// composeDescription joins Description, Detail, Advice, consequence advice
+// and labelled InfoLinks per language, separated by blank lines.
+func composeDescription(sx *siri.PtSituationElement) *gtfs.TranslatedString {
+	var bgParts, enParts []string
+	add := func(texts []siri.TranslatedText) {
+		bg, en := langTexts(texts)
+		if bg != "" {
+			bgParts = append(bgParts, bg)
+		}
+		if en != "" {
+			enParts = append(enParts, en)
+		}
+	}
+	add(sx.Descriptions)
+	add(sx.Details)
+	add(sx.Advices)
+	for _, c := range sx.Consequences {
+		if c.Advice != nil {
+			add(c.Advice.Details)
+		}
+	}
+	for _, l := range sx.InfoLinks {
+		link := infoLinkURL(l)
+		if link == "" || len(l.Labels) == 0 {
+			continue
+		}
+		bg, en := langTexts(l.Labels)
+		if bg != "" {
+			bgParts = append(bgParts, bg+": "+link)
+		}
+		if en != "" {
+			enParts = append(enParts, en+": "+link)
+		}
+	}
+	if len(bgParts) == 0 && len(enParts) == 0 {
+		return nil
+	}
+	return bgEnString(strings.Join(bgParts, "\n\n"), strings.Join(enParts, "\n\n"))
+}
+
+// passengerInformation picks the textual content of the first
+// PassengerInformationAction with a general perspective, or the first one.
+func passengerInformation(sx *siri.PtSituationElement) *siri.TextualContent {
+	var first *siri.TextualContent
+	for _, pa := range sx.PublishingActions {
+		for _, pia := range pa.PassengerInformationActions {
+			if len(pia.TextualContents) == 0 {
+				continue
+			}
+			tc := &pia.TextualContents[0]
+			if first == nil {
+				first = tc
+			}
+			for _, p := range pia.Perspectives {
+				if strings.EqualFold(strings.TrimSpace(p), "general") {
+					return tc
+				}
+			}
+		}
+	}
+	return first
+}
+
+// applyPassengerInformation uses SummaryText as header and joins the
+// description, consequence, recommendation, duration and remark texts.
+func applyPassengerInformation(tc *siri.TextualContent, alert *gtfs.Alert) {
+	if bg, en := langTexts(tc.SummaryTexts); bg != "" || en != "" {
+		alert.HeaderText = bgEnString(bg, en)
+	}
+	var bgParts, enParts []string
+	for _, texts := range [][]siri.TranslatedText{tc.DescriptionTexts, tc.ConsequenceTexts, tc.RecommendationTexts, tc.DurationTexts, tc.Remarks} {
+		bg, en := langTexts(texts)
+		if bg != "" {
+			bgParts = append(bgParts, bg)
+		}
+		if en != "" {
+			enParts = append(enParts, en)
+		}
+	}
+	if len(bgParts) > 0 || len(enParts) > 0 {
+		alert.DescriptionText = bgEnString(strings.Join(bgParts, "\n\n"), strings.Join(enParts, "\n\n"))
+	}
+}
+
+// langTexts returns the Bulgarian and English values, treating untagged text
+// as English like the header and description mapping.
+func langTexts(texts []siri.TranslatedText) (bg, en string) {
+	for _, t := range texts {
+		v := strings.TrimSpace(t.Value)
+		switch t.Lang {
+		case "bg":
+			bg = v
+		case "en", "":
+			en = v
+		}
+	}
+	return bg, en
+}
+
+func bgEnString(bg, en string) *gtfs.TranslatedString {
+	return &gtfs.TranslatedString{
+		Translation: []*gtfs.TranslatedString_Translation{
+			{Text: proto.String(bg), Language: strPtr("bg")},
+			{Text: proto.String(en), Language: strPtr("en")},
+		},
+	}
+}
+
+func copyTranslatedString(ts *gtfs.TranslatedString) *gtfs.TranslatedString {
+	if ts == nil {
+		return nil
+	}
+	return proto.Clone(ts).(*gtfs.TranslatedString)
+}
+
+func imageMediaType(u string) string {
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	switch strings.ToLower(path.Ext(u)) {
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".svg":
+		return "image/svg+xml"
+	case ".webp":
+		return "image/webp"
+	default:
+		return "image/*"
+	}
+}
+
+// infoLinkURL returns the link target of either InfoLink form.
+func infoLinkURL(l siri.InfoLink) string {
+	if l.Href != "" {
+		return strings.TrimSpace(l.Href)
+	}
+	return strings.TrimSpace(l.Uri)
+}
@@ -0,0 +1,163 @@
+package converter
+
+import (
+	"fmt"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// CM -> TripUpdate holds / Alert
+
+// Interchange is a guaranteed connection known to the interchange system,
+// keyed in Options.Interchanges by its SIRI InterchangeRef.
+type Interchange struct {
+	DistributorTripID string        // GTFS trip_id of the departing (distributor) journey
+	StopID            string        // departure stop of the distributor; defaults to the feeder StopPointRef
+	MinTransfer       time.Duration // time needed to change between the two journeys
+	MaxWait           time.Duration // longest hold of the distributor departure (zero: unlimited)
+}
+
+// applyFeederArrivals holds distributor departures for guaranteed
+// connections whose feeder arrives late. Only trip updates already in out
+// are adjusted: without a predicted departure there is nothing to hold.
+func applyFeederArrivals(out []Entity, arrivals []siri.MonitoredFeederArrival, opts Options) []Entity {
+	for _, fa := range arrivals {
+		ic, ok := opts.Interchanges[derefString(fa.InterchangeRef)]
+		if !ok || ic.DistributorTripID == "" || fa.ExpectedArrivalTime == nil {
+			continue
+		}
+		arrival, ok := siri.ParseISOTime(*fa.ExpectedArrivalTime)
+		if !ok {
+			continue
+		}
+		stopID := ic.StopID
+		if stopID == "" {
+			stopID = stripPrefix(derefString(fa.StopPointRef), "SOFIA:Quay:")
+		}
+		required := arrival.Add(ic.MinTransfer)
+		if tu := findTripUpdate(out, ic.DistributorTripID, stopID, required); tu != nil {
+			holdDeparture(tu, stopID, required, ic.MaxWait)
+		}
+	}
+	return out
+}
+
+// findTripUpdate returns the trip update of tripID whose departure from
+// stopID is closest to the required departure. The distributor's service day
+// thus comes from its own trip update, not from the feeder, whose service
+// day differs when the connection spans midnight.
+func findTripUpdate(ents []Entity, tripID, stopID string, required time.Time) *gtfs.TripUpdate {
+	var best *gtfs.TripUpdate
+	var bestGap time.Duration
+	for i := range ents {
+		tu := ents[i].Message.GetTripUpdate()
+		if tu.GetTrip().GetTripId() != tripID {
+			continue
+		}
+		k := departureIndex(tu, stopID)
+		if k < 0 {
+			continue
+		}
+		gap := required.Sub(time.Unix(tu.StopTimeUpdate[k].Departure.GetTime(), 0)).Abs()
+		if best == nil || gap < bestGap {
+			best, bestGap = tu, gap
+		}
+	}
+	return best
+}
+
+func departureIndex(tu *gtfs.TripUpdate, stopID string) int {
+	for i, stu := range tu.StopTimeUpdate {
+		if stu.GetStopId() == stopID && stu.Departure.GetTime() != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// holdDeparture delays the departure at stopID to required when it is
+// earlier, but by no more than maxWait, and propagates the hold to the
+// later stops of the trip.
+func holdDeparture(tu *gtfs.TripUpdate, stopID string, required time.Time, maxWait time.Duration) {
+	i := departureIndex(tu, stopID)
+	if i < 0 {
+		return
+	}
+	departure := time.Unix(tu.StopTimeUpdate[i].Departure.GetTime(), 0)
+	if !required.After(departure) {
+		return
+	}
+	hold := required.Sub(departure)
+	if maxWait > 0 && hold > maxWait {
+		hold = maxWait
+	}
+	shiftEvent(tu.StopTimeUpdate[i].Departure, hold)
+	for j := i + 1; j < len(tu.StopTimeUpdate); j++ {
+		shiftEvent(tu.StopTimeUpdate[j].Arrival, hold)
+		shiftEvent(tu.StopTimeUpdate[j].Departure, hold)
+	}
+}
+
+func shiftEvent(ev *gtfs.TripUpdate_StopTimeEvent, d time.Duration) {
+	if ev == nil {
+		return
+	}
+	secs := int64(d / time.Second)
+	if ev.GetTime() != 0 {
+		ev.Time = proto.Int64(ev.GetTime() + secs)
+	}
+	if ev.Delay != nil {
+		ev.Delay = proto.Int32(*ev.Delay + int32(secs))
+	}
+}
+
+// MapCMCancellationToAlert informs riders at the interchange that a
+// connection is no longer guaranteed. The distributor trip at the stop is
+// informed when the interchange is configured, the stop alone otherwise.
+func MapCMCancellationToAlert(c *siri.MonitoredFeederArrivalCancellation, opts Options) *Entity {
+	if c == nil || c.StopPointRef == nil {
+		return nil
+	}
+	stopID := stripPrefix(*c.StopPointRef, "SOFIA:Quay:")
+	key := derefString(c.InterchangeRef)
+	if key == "" {
+		key = stopID
+		if c.VehicleJourneyRef != nil && c.VehicleJourneyRef.DatedVehicleJourneyRef != nil {
+			key += "-" + stripPrefix(*c.VehicleJourneyRef.DatedVehicleJourneyRef, "SOFIA:ServiceJourney:")
+		}
+	}
+	id := "connection-" + key
+
+	var informed []*gtfs.EntitySelector
+	if ic, ok := opts.Interchanges[derefString(c.InterchangeRef)]; ok && ic.DistributorTripID != "" {
+		if ic.StopID != "" {
+			stopID = ic.StopID
+		}
+		informed = append(informed, &gtfs.EntitySelector{StopId: &stopID, Trip: &gtfs.TripDescriptor{TripId: proto.String(ic.DistributorTripID)}})
+	} else {
+		informed = append(informed, &gtfs.EntitySelector{StopId: &stopID})
+	}
+
+	line := stripPrefix(derefString(c.LineRef), "SOFIA:Line:")
+	bg, en := "Връзката не е гарантирана", "Connection not guaranteed"
+	if line != "" {
+		bg = fmt.Sprintf("Връзката с линия %s не е гарантирана", line)
+		en = fmt.Sprintf("Connection with line %s not guaranteed", line)
+	}
+	alert := &gtfs.Alert{
+		Cause:          gtfs.Alert_UNKNOWN_CAUSE.Enum(),
+		Effect:         gtfs.Alert_OTHER_EFFECT.Enum(),
+		HeaderText:     bgEnString(bg, en),
+		InformedEntity: informed,
+	}
+	if rbg, ren := langTexts(c.Reasons); rbg != "" || ren != "" {
+		alert.DescriptionText = bgEnString(rbg, ren)
+	}
+
+	ent := &gtfs.FeedEntity{Id: &id, IsDeleted: proto.Bool(false), Alert: alert}
+	return &Entity{ID: id, Kind: "alert", Message: ent, TTL: opts.VMGracePeriod}
+}
@@ -1,6 +1,10 @@
 package converter
 
 import (
+	"context"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+
 	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
 	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
 )
@@ -8,7 +12,14 @@ import (
 // Convert and feed building logic
 
 func ConvertSIRI(sd *siri.ServiceDelivery, opts Options) ([]Entity, error) {
-	return convertSIRI(sd, opts)
+	return convertSIRI(context.Background(), sd, opts)
+}
+
+// ConvertSIRIContext is ConvertSIRI with cancellation: it stops early and
+// returns ctx.Err() when ctx is done. Set Options.Workers to map ET journeys
+// concurrently; the output order does not depend on the worker count.
+func ConvertSIRIContext(ctx context.Context, sd *siri.ServiceDelivery, opts Options) ([]Entity, error) {
+	return convertSIRI(ctx, sd, opts)
 }
 
 func BuildFeedMessage(entities []Entity) *gtfsrt.FeedMessage {
@@ -19,48 +30,170 @@ func BuildPerDatasource(entities []Entity) map[string]*gtfsrt.FeedMessage {
 	return buildPerDatasource(entities)
 }
 
-func convertSIRI(sd *siri.ServiceDelivery, opts Options) ([]Entity, error) {
+func convertSIRI(ctx context.Context, sd *siri.ServiceDelivery, opts Options) ([]Entity, error) {
 	var out []Entity
 	if sd == nil {
 		return out, nil
 	}
 
+	// Shared trackers are pruned here so that long-running callers do not
+	// have to.
+	now := opts.now()
+	if opts.VehicleHistory != nil {
+		opts.VehicleHistory.Prune(now)
+	}
+	if opts.SituationVersions != nil && opts.SituationVersions.Retention > 0 {
+		opts.SituationVersions.Prune(now.Add(-opts.SituationVersions.Retention))
+	}
+	if opts.Timetable != nil && opts.Timetable.Retention > 0 {
+		opts.Timetable.Prune(now.Add(-opts.Timetable.Retention))
+	}
+
+	// Load planned journeys first so that ET in the same delivery can use
+	// them, even without a shared timetable.
+	if len(sd.ProductionTimetableDeliveries) > 0 && opts.Timetable == nil {
+		opts.Timetable = NewTimetable()
+	}
+	for i := range sd.ProductionTimetableDeliveries {
+		opts.Timetable.Load(&sd.ProductionTimetableDeliveries[i])
+	}
+
+	var synthetic []Entity
+	reported := make(map[string]bool)
+	var estimated []*gtfs.TripDescriptor
+
+	var journeys []*siri.EstimatedVehicleJourney
 	for _, d := range sd.EstimatedTimetableDeliveries {
 		for _, f := range d.EstimatedJourneyVersionFrames {
-			for _, evj := range f.EstimatedVehicleJourneys {
-				if e := MapETToTripUpdate(&evj, opts); e != nil {
-					e.Kind = "trip_update"
-					out = append(out, *e)
-				}
+			for i := range f.EstimatedVehicleJourneys {
+				journeys = append(journeys, &f.EstimatedVehicleJourneys[i])
 			}
 		}
 	}
+	// Results are stored by journey index so the output order is the same
+	// with any number of workers.
+	tripUpdates := make([]*Entity, len(journeys))
+	tripAlerts := make([]*Entity, len(journeys))
+	err := forEachIndex(ctx, len(journeys), opts.Workers, func(i int) {
+		tripUpdates[i] = MapETToTripUpdate(journeys[i], opts)
+		if opts.SyntheticAlerts != nil {
+			tripAlerts[i] = tripUpdateAlert(journeys[i], tripUpdates[i], opts)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	out = make([]Entity, 0, len(journeys))
+	for i, e := range tripUpdates {
+		if e != nil {
+			e.Kind = "trip_update"
+			out = append(out, *e)
+			reported[e.ID] = true
+			estimated = append(estimated, e.Message.TripUpdate.Trip)
+		}
+		if a := tripAlerts[i]; a != nil {
+			synthetic = append(synthetic, *a)
+		}
+	}
 
+	if opts.PTEmitExtraJourneys && opts.Timetable != nil {
+		for _, pj := range opts.Timetable.extraJourneys(opts.now()) {
+			if !reported[pj.key] {
+				out = append(out, *plannedTripUpdate(pj, opts))
+			}
+		}
+	}
+
+	// Stop visits are aggregated across deliveries: each monitored stop
+	// usually comes in its own StopMonitoringDelivery.
+	var visits []siri.MonitoredStopVisit
+	for _, d := range sd.StopMonitoringDeliveries {
+		visits = append(visits, d.MonitoredStopVisits...)
+	}
+	// ET is the richer source: a journey it already reports for the same
+	// service day is not emitted again from stop visits.
+	out = append(out, withoutEstimated(MapSMToTripUpdates(visits, opts), estimated)...)
+
+	for _, d := range sd.ConnectionMonitoringFeederDeliveries {
+		out = applyFeederArrivals(out, d.MonitoredFeederArrivals, opts)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var vehicles []Entity
 	for _, d := range sd.VehicleMonitoringDeliveries {
 		for _, va := range d.VehicleActivities {
-			if e := MapVMToVehiclePosition(&va, opts); e != nil {
-				e.Kind = "vehicle_position"
-				out = append(out, *e)
+			e := MapVMToVehiclePosition(&va, opts)
+			if e == nil {
+				continue
+			}
+			e.Kind = "vehicle_position"
+			vehicles = append(vehicles, *e)
+			// Rejected or stale activities do not raise delay alerts.
+			if a := MapVMToAlert(&va, opts); a != nil {
+				synthetic = append(synthetic, *a)
 			}
 		}
 	}
+	for _, v := range visits {
+		if e := MapSMToVehiclePosition(&v, opts); e != nil {
+			e.Kind = "vehicle_position"
+			vehicles = append(vehicles, *e)
+		}
+	}
+	out = append(out, dedupeVehiclePositions(vehicles, opts)...)
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Track versions within this delivery even without a shared tracker so
+	// that the newest copy of a repeated situation wins.
+	sxOpts := opts
+	if sxOpts.SituationVersions == nil {
+		sxOpts.SituationVersions = NewSituationVersions()
+	}
+	var alerts []Entity
 	for _, d := range sd.SituationExchangeDeliveries {
 		for _, sx := range d.Situations {
-			if e := MapSXToAlert(&sx, opts); e != nil {
+			if e := MapSXToAlert(&sx, sxOpts); e != nil {
 				e.Kind = "alert"
-				out = append(out, *e)
+				alerts = append(alerts, *e)
+			}
+		}
+	}
+	// Only SX situations replace synthetic alerts: FM and CM alerts inform
+	// the same trips for unrelated reasons.
+	situations := keepLastByID(alerts)
+	for _, d := range sd.FacilityMonitoringDeliveries {
+		for _, fc := range d.FacilityConditions {
+			if e := MapFMToAlert(&fc, opts); e != nil {
+				alerts = append(alerts, *e)
+			}
+		}
+	}
+	for _, d := range sd.ConnectionMonitoringFeederDeliveries {
+		for _, c := range d.MonitoredFeederArrivalCancellations {
+			if e := MapCMCancellationToAlert(&c, opts); e != nil {
+				alerts = append(alerts, *e)
 			}
 		}
 	}
+	alerts = keepLastByID(alerts)
+	out = append(out, alerts...)
+	out = append(out, withoutSituations(keepLastByID(synthetic), situations)...)
 	return out, nil
 }
 
+// buildFeedMessage builds the JSON view of a FULL_DATASET message, which
+// must not carry is_deleted entities.
 func buildFeedMessage(entities []Entity) *gtfsrt.FeedMessage {
 	msg := gtfsrt.NewFeedMessage()
 	for _, e := range entities {
-		if e.Message != nil {
-			msg.Entity = append(msg.Entity, e.Message)
+		if e.Message != nil && !e.Deleted {
+			msg.Entity = append(msg.Entity, gtfsrt.FromProtoEntity(e.Message))
 		}
 	}
 	return msg
@@ -69,7 +202,7 @@ func buildFeedMessage(entities []Entity) *gtfsrt.FeedMessage {
 func buildPerDatasource(entities []Entity) map[string]*gtfsrt.FeedMessage {
 	out := make(map[string]*gtfsrt.FeedMessage)
 	for _, e := range entities {
-		if e.Message == nil {
+		if e.Message == nil || e.Deleted {
 			continue
 		}
 		msg, ok := out[e.Datasource]
@@ -77,7 +210,7 @@ func buildPerDatasource(entities []Entity) map[string]*gtfsrt.FeedMessage {
 			msg = gtfsrt.NewFeedMessage()
 			out[e.Datasource] = msg
 		}
-		msg.Entity = append(msg.Entity, e.Message)
+		msg.Entity = append(msg.Entity, gtfsrt.FromProtoEntity(e.Message))
 	}
 	return out
 }
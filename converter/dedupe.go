@@ -0,0 +1,93 @@
+package converter
+
+// EntityIDStrategy selects how vehicle position entity IDs are built.
+type EntityIDStrategy int
+
+const (
+	// EntityIDTrip uses "<trip>-<date>", falling back to the vehicle ID.
+	// Coupled vehicles on one journey collapse into a single entity.
+	EntityIDTrip EntityIDStrategy = iota
+	// EntityIDVehicle uses the vehicle ID, falling back to the trip.
+	EntityIDVehicle
+	// EntityIDTripVehicle uses "<trip>-<date>-<vehicle>" so coupled vehicles
+	// and interlined journeys each get their own entity.
+	EntityIDTripVehicle
+)
+
+func vehiclePositionID(tripKey, vehicleKey string, strategy EntityIDStrategy) string {
+	switch strategy {
+	case EntityIDVehicle:
+		if vehicleKey != "" {
+			return vehicleKey
+		}
+		return tripKey
+	case EntityIDTripVehicle:
+		if tripKey != "" && vehicleKey != "" {
+			return tripKey + "-" + vehicleKey
+		}
+		if tripKey != "" {
+			return tripKey
+		}
+		return vehicleKey
+	default:
+		if tripKey != "" {
+			return tripKey
+		}
+		return vehicleKey
+	}
+}
+
+// dedupeVehiclePositions removes entities sharing an ID and, when configured,
+// multiple entities for the same vehicle. The newest timestamp wins; on a tie
+// the later record in the delivery wins. Output keeps first-seen order.
+func dedupeVehiclePositions(ents []Entity, opts Options) []Entity {
+	ents = dedupeBy(ents, func(e Entity) string { return e.ID })
+	if opts.VMOneEntityPerVehicle {
+		ents = dedupeBy(ents, func(e Entity) string {
+			return e.Message.GetVehicle().GetVehicle().GetId()
+		})
+	}
+	return ents
+}
+
+func dedupeBy(ents []Entity, key func(Entity) string) []Entity {
+	index := make(map[string]int, len(ents))
+	out := ents[:0:0]
+	for _, e := range ents {
+		k := key(e)
+		if k == "" {
+			out = append(out, e)
+			continue
+		}
+		i, seen := index[k]
+		if !seen {
+			index[k] = len(out)
+			out = append(out, e)
+			continue
+		}
+		if vehicleTimestamp(e) >= vehicleTimestamp(out[i]) {
+			out[i] = e
+		}
+	}
+	return out
+}
+
+func vehicleTimestamp(e Entity) int64 {
+	return int64(e.Message.GetVehicle().GetTimestamp())
+}
+
+// keepLastByID keeps the last entity for every ID at the position of its
+// first occurrence.
+func keepLastByID(ents []Entity) []Entity {
+	index := make(map[string]int, len(ents))
+	out := ents[:0:0]
+	for _, e := range ents {
+		if i, seen := index[e.ID]; seen {
+			out[i] = e
+			continue
+		}
+		index[e.ID] = len(out)
+		out = append(out, e)
+	}
+	return out
+}
@@ -0,0 +1,217 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"sort"
+	"sync"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+)
+
+// DefaultTombstoneRetention is how long NewDifferential keeps deletions.
+const DefaultTombstoneRetention = 10 * time.Minute
+
+// Differential tracks successive conversions so that DIFFERENTIAL feeds
+// with only the entities changed since a client's last version can be
+// served next to the full dataset. Every Update is a new version; entities
+// missing from an update, or converted as is_deleted, are reported as
+// is_deleted tombstones for Retention. It is safe for concurrent use.
+type Differential struct {
+	// Retention is how long tombstones are served after the deletion
+	// (zero: until the entity reappears).
+	Retention time.Duration
+	// Now overrides the clock used to time deletions (time.Now when nil).
+	Now func() time.Time
+
+	mu       sync.Mutex
+	version  uint64
+	floor    uint64                // newest version of a dropped tombstone
+	entities map[string]*diffEntry // by kind and entity ID
+}
+
+type diffEntry struct {
+	entity    Entity
+	digest    [sha256.Size]byte
+	version   uint64 // version of the last change
+	deleted   bool
+	deletedAt time.Time
+}
+
+// NewDifferential returns a tracker at version 0 that keeps tombstones for
+// DefaultTombstoneRetention.
+func NewDifferential() *Differential {
+	return &Differential{Retention: DefaultTombstoneRetention, entities: make(map[string]*diffEntry)}
+}
+
+// Version returns the version of the latest Update.
+func (d *Differential) Version() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.version
+}
+
+// Len returns the number of entities tracked, deletions included.
+func (d *Differential) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.entities)
+}
+
+// Covers reports whether Changes(version) still includes every deletion
+// since version. Clients behind the retention window, or ahead of the
+// current version (e.g. after a restart), must reload the full dataset.
+func (d *Differential) Covers(version uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return version == 0 || (version >= d.floor && version <= d.version)
+}
+
+// Update records the result of a conversion and returns its version.
+// It is a complete snapshot only of the kinds it contains: a VM-only poll
+// deletes vehicle positions that are gone but leaves trip updates and
+// alerts alone. Use UpdateKinds when a kind may legitimately come back
+// empty. Tombstones older than Retention are dropped.
+func (d *Differential) Update(entities []Entity) uint64 {
+	var kinds []string
+	present := make(map[string]bool)
+	for _, e := range entities {
+		if e.Message != nil && !present[e.Kind] {
+			present[e.Kind] = true
+			kinds = append(kinds, e.Kind)
+		}
+	}
+	return d.UpdateKinds(entities, kinds...)
+}
+
+// UpdateKinds is Update with an explicit scope: entities of the given
+// kinds ("trip_update", "vehicle_position", "alert") that are missing from
+// entities are deleted, even when none of that kind remain.
+func (d *Differential) UpdateKinds(entities []Entity, kinds ...string) uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	scope := make(map[string]bool, len(kinds))
+	for _, k := range kinds {
+		scope[k] = true
+	}
+	if d.entities == nil {
+		d.entities = make(map[string]*diffEntry)
+	}
+	now := time.Now()
+	if d.Now != nil {
+		now = d.Now()
+	}
+	d.version++
+	seen := make(map[string]bool, len(entities))
+	for _, e := range entities {
+		if e.Message == nil {
+			continue
+		}
+		key := e.Kind + "/" + e.ID
+		prev, ok := d.entities[key]
+		if e.Deleted || e.Message.GetIsDeleted() {
+			// Deleted upstream (e.g. a closed situation); a missing entity
+			// is handled below.
+			if ok && !prev.deleted {
+				d.markDeleted(prev, e, now)
+			}
+			seen[key] = ok
+			continue
+		}
+		seen[key] = true
+		digest := entityDigest(e.Message)
+		if ok && !prev.deleted && prev.digest == digest {
+			prev.entity = e
+			continue
+		}
+		d.entities[key] = &diffEntry{entity: e, digest: digest, version: d.version}
+	}
+	for key, de := range d.entities {
+		switch {
+		case !seen[key] && !de.deleted && scope[de.entity.Kind]:
+			d.markDeleted(de, de.entity, now)
+		case de.deleted && d.Retention > 0 && now.Sub(de.deletedAt) > d.Retention:
+			delete(d.entities, key)
+			if de.version > d.floor {
+				d.floor = de.version
+			}
+		}
+	}
+	return d.version
+}
+
+func (d *Differential) markDeleted(de *diffEntry, e Entity, now time.Time) {
+	de.entity = tombstone(de.entity)
+	if e.Datasource != "" {
+		de.entity.Datasource = e.Datasource
+	}
+	if d.Retention > 0 {
+		de.entity.TTL = d.Retention
+	}
+	de.deleted = true
+	de.deletedAt = now
+	de.version = d.version
+}
+
+// Changes returns the entities added, changed or deleted after version,
+// ordered by kind and ID, and the current version. Version 0 yields every
+// live entity without deletions, as does a version newer than the current
+// one; publish that set with BuildFeedMessage as a FULL_DATASET, never as
+// DIFFERENTIAL. See Covers for versions older than the retention window.
+func (d *Differential) Changes(version uint64) ([]Entity, uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if version > d.version {
+		version = 0
+	}
+	keys := make([]string, 0, len(d.entities))
+	for key, de := range d.entities {
+		if de.version > version && (version > 0 || !de.deleted) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	out := make([]Entity, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, d.entities[key].entity)
+	}
+	return out, d.version
+}
+
+// BuildDifferentialFeedMessage builds a DIFFERENTIAL message, tombstones
+// included, for the entities returned by Differential.Changes for a version
+// that Covers accepts. Clients reset to version 0 need BuildFeedMessage.
+func BuildDifferentialFeedMessage(entities []Entity) *gtfsrt.FeedMessage {
+	msg := &gtfsrt.FeedMessage{Header: gtfsrt.NewDifferentialFeedMessageHeader()}
+	for _, e := range entities {
+		if e.Message != nil {
+			msg.Entity = append(msg.Entity, gtfsrt.FromProtoEntity(e.Message))
+		}
+	}
+	return msg
+}
+
+// entityDigest hashes the protobuf encoding, extension fields included.
+func entityDigest(fe *gtfs.FeedEntity) [sha256.Size]byte {
+	b, _ := proto.MarshalOptions{Deterministic: true}.Marshal(fe)
+	return sha256.Sum256(b)
+}
+
+// tombstone reduces an entity to what identifies it, flagged is_deleted.
+func tombstone(e Entity) Entity {
+	fe := &gtfs.FeedEntity{Id: proto.String(e.ID), IsDeleted: proto.Bool(true)}
+	if m := e.Message; m != nil {
+		switch {
+		case m.TripUpdate != nil:
+			fe.TripUpdate = &gtfs.TripUpdate{Trip: m.TripUpdate.Trip, Vehicle: m.TripUpdate.Vehicle}
+		case m.Vehicle != nil:
+			fe.Vehicle = &gtfs.VehiclePosition{Trip: m.Vehicle.Trip, Vehicle: m.Vehicle.Vehicle}
+		case m.Alert != nil:
+			fe.Alert = &gtfs.Alert{InformedEntity: m.Alert.InformedEntity}
+		}
+	}
+	return Entity{ID: e.ID, Datasource: e.Datasource, Kind: e.Kind, Message: fe, TTL: e.TTL, Deleted: true}
+}
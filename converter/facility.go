@@ -0,0 +1,127 @@
+package converter
+
+import (
+	"strings"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// FM -> Alert
+
+// MapFMToAlert converts an out-of-service (or partially available) facility
+// into an ACCESSIBILITY_ISSUE alert informing the stop it belongs to.
+// Available facilities, conditions whose validity has ended and facilities
+// that cannot be located at a stop yield nil.
+func MapFMToAlert(fc *siri.FacilityCondition, opts Options) *Entity {
+	if fc == nil {
+		return nil
+	}
+	ref := derefString(fc.FacilityRef)
+	if ref == "" && fc.Facility != nil {
+		ref = derefString(fc.Facility.FacilityCode)
+	}
+	if ref == "" {
+		return nil
+	}
+	id := "facility-" + stripPrefix(ref, "SOFIA:Facility:")
+
+	status := facilityStatus(fc)
+	if status != "notavailable" && status != "partiallyavailable" {
+		return nil
+	}
+
+	now := opts.now()
+	ttl := opts.SXDefaultTTL
+	var active []*gtfs.TimeRange
+	if vp := fc.ValidityPeriod; vp != nil && (vp.StartTime != nil || vp.EndTime != nil) {
+		active = append(active, timeRange(vp.StartTime, vp.EndTime))
+		if vp.EndTime != nil {
+			if end, ok := siri.ParseISOTime(*vp.EndTime); ok {
+				if !now.Before(end) {
+					opts.reject("alert", id, RejectExpired)
+					return nil
+				}
+				ttl = end.Sub(now)
+			}
+		}
+	}
+
+	var informed []*gtfs.EntitySelector
+	var class string
+	var descriptions []siri.TranslatedText
+	if f := fc.Facility; f != nil {
+		class = strings.ToLower(strings.TrimSpace(derefString(f.FacilityClass)))
+		descriptions = f.Descriptions
+		if loc := f.FacilityLocation; loc != nil {
+			if loc.StopPointRef != nil {
+				sid := stripPrefix(*loc.StopPointRef, "SOFIA:Quay:")
+				informed = append(informed, &gtfs.EntitySelector{StopId: &sid})
+			}
+			if loc.StopPlaceRef != nil {
+				informed = append(informed, stopPlaceSelectors(siri.AffectedStopPlace{StopPlaceRef: loc.StopPlaceRef}, opts)...)
+			}
+			if loc.StopPlaceComponentRef != nil {
+				informed = append(informed, componentSelectors(*loc.StopPlaceComponentRef, false, opts)...)
+			}
+		}
+	}
+	if len(informed) == 0 {
+		// A bare FacilityRef cannot be placed; an alert must inform something.
+		opts.reject("alert", id, RejectNoInformedEntity)
+		return nil
+	}
+	if fc.FacilityStatus != nil && len(fc.FacilityStatus.Descriptions) > 0 {
+		descriptions = fc.FacilityStatus.Descriptions
+	}
+
+	bg, en := facilityHeader(class, status == "partiallyavailable")
+	alert := &gtfs.Alert{
+		ActivePeriod:   active,
+		Cause:          gtfs.Alert_UNKNOWN_CAUSE.Enum(),
+		Effect:         gtfs.Alert_ACCESSIBILITY_ISSUE.Enum(),
+		HeaderText:     bgEnString(bg, en),
+		InformedEntity: dedupeSelectors(informed),
+	}
+	if dbg, den := langTexts(descriptions); dbg != "" || den != "" {
+		alert.DescriptionText = bgEnString(dbg, den)
+	}
+
+	ent := &gtfs.FeedEntity{Id: &id, IsDeleted: proto.Bool(false), Alert: alert}
+	return &Entity{ID: id, Kind: "alert", Message: ent, TTL: ttl}
+}
+
+// facilityStatus returns the lower-cased FacilityStatus/Status. A facility
+// without a status counts as not available when its accessibility assessment
+// reports reduced access.
+func facilityStatus(fc *siri.FacilityCondition) string {
+	if fc.FacilityStatus == nil {
+		return ""
+	}
+	status := strings.ToLower(strings.TrimSpace(derefString(fc.FacilityStatus.Status)))
+	if (status == "" || status == "unknown") && reducedAccessibility(fc.FacilityStatus.AccessibilityAssessment) {
+		return "notavailable"
+	}
+	return status
+}
+
+func facilityHeader(class string, partial bool) (bg, en string) {
+	switch class {
+	case "lift", "elevator":
+		bg, en = "Асансьорът", "Elevator"
+	case "escalator":
+		bg, en = "Ескалаторът", "Escalator"
+	case "travelator":
+		bg, en = "Подвижната пътека", "Travelator"
+	case "ramp":
+		bg, en = "Рампата", "Ramp"
+	default:
+		bg, en = "Съоръжението", "Facility"
+	}
+	if partial {
+		return bg + " работи частично", en + " partially available"
+	}
+	return bg + " не работи", en + " out of service"
+}
@@ -0,0 +1,136 @@
+package converter
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+)
+
+// VehicleHistory remembers the last position of each vehicle (keyed by
+// VehicleRef) so that bearing and speed can be derived when a producer omits
+// them. Set Options.VehicleHistory to enable derivation; the same history must
+// be reused across conversions. It is safe for concurrent use.
+type VehicleHistory struct {
+	// MinDistance is the movement in metres below which a new sample is
+	// treated as GPS jitter: speed is reported as 0 and the previous bearing kept.
+	MinDistance float64
+	// MaxSampleAge ignores previous samples older than this.
+	MaxSampleAge time.Duration
+	// MaxSpeed in m/s; faster derived speeds are treated as position jumps.
+	MaxSpeed float64
+
+	mu      sync.Mutex
+	samples map[string]vehicleSample
+}
+
+type vehicleSample struct {
+	at      time.Time
+	lat     float64
+	lon     float64
+	bearing *float32
+}
+
+// NewVehicleHistory returns a history with defaults suited to urban buses and trains.
+func NewVehicleHistory() *VehicleHistory {
+	return &VehicleHistory{
+		MinDistance:  10,
+		MaxSampleAge: 2 * time.Minute,
+		MaxSpeed:     70, // ~250 km/h
+		samples:      make(map[string]vehicleSample),
+	}
+}
+
+// Len returns the number of vehicles currently tracked.
+func (h *VehicleHistory) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.samples)
+}
+
+// Prune drops samples recorded more than MaxSampleAge before now.
+func (h *VehicleHistory) Prune(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for k, s := range h.samples {
+		if now.Sub(s.at) > h.MaxSampleAge {
+			delete(h.samples, k)
+		}
+	}
+}
+
+// derive fills missing bearing and speed on pos from the previous sample of
+// the vehicle and records the new sample.
+func (h *VehicleHistory) derive(vehicle string, at time.Time, lat, lon float64, pos *gtfs.Position) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.samples == nil {
+		h.samples = make(map[string]vehicleSample)
+	}
+	cur := vehicleSample{at: at, lat: lat, lon: lon, bearing: pos.Bearing}
+
+	prev, ok := h.samples[vehicle]
+	if !ok {
+		h.samples[vehicle] = cur
+		return
+	}
+	dt := at.Sub(prev.at)
+	if dt <= 0 {
+		// Duplicate or out-of-order sample; keep the newer history.
+		return
+	}
+	if dt > h.MaxSampleAge {
+		h.samples[vehicle] = cur
+		return
+	}
+
+	dist := haversineMeters(prev.lat, prev.lon, lat, lon)
+	if dist < h.MinDistance {
+		// Jitter: keep the anchor so slow movement accumulates.
+		if pos.Speed == nil {
+			zero := float32(0)
+			pos.Speed = &zero
+		}
+		if pos.Bearing == nil && prev.bearing != nil {
+			b := *prev.bearing
+			pos.Bearing = &b
+		}
+		return
+	}
+
+	speed := dist / dt.Seconds()
+	if speed <= h.MaxSpeed {
+		if pos.Speed == nil {
+			sp := float32(speed)
+			pos.Speed = &sp
+		}
+		if pos.Bearing == nil {
+			b := float32(initialBearing(prev.lat, prev.lon, lat, lon))
+			pos.Bearing = &b
+		}
+	}
+	cur.bearing = pos.Bearing
+	h.samples[vehicle] = cur
+}
+
+const earthRadiusMeters = 6371008.8
+
+func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	p1, p2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dp := p2 - p1
+	dl := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dp/2)*math.Sin(dp/2) + math.Cos(p1)*math.Cos(p2)*math.Sin(dl/2)*math.Sin(dl/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// initialBearing returns the compass bearing in [0,360) from point 1 to point 2.
+func initialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	p1, p2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dl := (lon2 - lon1) * math.Pi / 180
+	y := math.Sin(dl) * math.Cos(p2)
+	x := math.Cos(p1)*math.Sin(p2) - math.Sin(p1)*math.Cos(p2)*math.Cos(dl)
+	b := math.Atan2(y, x) * 180 / math.Pi
+	return math.Mod(b+360, 360)
+}
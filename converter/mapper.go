@@ -1,11 +1,13 @@
 package converter
 
 import (
-	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
-	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
 	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
 )
 
@@ -17,64 +19,72 @@ func MapVMToVehiclePosition(va *siri.VehicleActivity, opts Options) *Entity {
 		return nil
 	}
 	mvj := va.MonitoredVehicleJourney
-	if mvj.VehicleLocation == nil || (mvj.FramedVehicleJourneyRef == nil && mvj.VehicleRef == nil) {
+	if mvj.FramedVehicleJourneyRef == nil && mvj.VehicleRef == nil {
 		return nil
 	}
 
-	var id string
-	// Prefer trip ID over vehicle ID for entity ID
+	var tripKey, vehicleKey string
 	if mvj.FramedVehicleJourneyRef != nil && mvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef != nil {
-		id = stripPrefix(*mvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef, "SOFIA:ServiceJourney:")
+		tripKey = stripPrefix(*mvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef, "SOFIA:ServiceJourney:")
 		if mvj.OriginAimedDepartureTime != nil {
 			if t, ok := siri.ParseISOTime(*mvj.OriginAimedDepartureTime); ok {
-				id = id + "-" + siri.FormatDateYYYYMMDD(t)
+				tripKey = tripKey + "-" + siri.FormatDateYYYYMMDD(t)
 			}
 		}
-	} else if mvj.VehicleRef != nil && *mvj.VehicleRef != "" {
-		id = stripPrefix(*mvj.VehicleRef, "SOFIA:VehicleRef:")
 	}
+	if mvj.VehicleRef != nil && *mvj.VehicleRef != "" {
+		vehicleKey = stripPrefix(*mvj.VehicleRef, "SOFIA:VehicleRef:")
+	}
+	id := vehiclePositionID(tripKey, vehicleKey, opts.VMEntityID)
 	if id == "" {
 		return nil
 	}
 
+	pos, reason := validatePosition(mvj, opts)
+	if pos == nil {
+		opts.reject("vehicle_position", id, reason)
+		return nil
+	}
+	now := opts.now()
+	if reason := checkFreshness(va, now, opts); reason != "" {
+		opts.reject("vehicle_position", id, reason)
+		return nil
+	}
+
 	ttl := opts.VMGracePeriod
 	if va.ValidUntilTime != nil {
 		if t, ok := siri.ParseISOTime(*va.ValidUntilTime); ok {
-			d := time.Until(t)
+			d := t.Sub(now)
 			if d > 0 {
 				ttl = d
 			}
 		}
 	}
 
-	ent := &gtfsrt.FeedEntity{Id: &id}
-	vp := &gtfsrt.VehiclePosition{}
+	ent := &gtfs.FeedEntity{Id: &id}
+	vp := &gtfs.VehiclePosition{}
 
 	// Map congestion level from InCongestion
 	if mvj.InCongestion != nil {
 		if *mvj.InCongestion {
-			congestionLevel := int32(3) // CONGESTION
-			vp.CongestionLevel = &congestionLevel
+			vp.CongestionLevel = gtfs.VehiclePosition_CONGESTION.Enum()
 		} else {
-			congestionLevel := int32(0) // UNKNOWN_CONGESTION_LEVEL
-			vp.CongestionLevel = &congestionLevel
+			vp.CongestionLevel = gtfs.VehiclePosition_UNKNOWN_CONGESTION_LEVEL.Enum()
 		}
 	}
 
 	// Map occupancy status from Occupancy
 	if mvj.Occupancy != nil {
-		var occupancyStatus int32
+		occupancyStatus := gtfs.VehiclePosition_EMPTY
 		switch *mvj.Occupancy {
 		case "manySeatsAvailable":
-			occupancyStatus = 1
+			occupancyStatus = gtfs.VehiclePosition_MANY_SEATS_AVAILABLE
 		case "seatsAvailable":
-			occupancyStatus = 2
+			occupancyStatus = gtfs.VehiclePosition_FEW_SEATS_AVAILABLE
 		case "standingAvailable":
-			occupancyStatus = 3
+			occupancyStatus = gtfs.VehiclePosition_STANDING_ROOM_ONLY
 		case "full":
-			occupancyStatus = 5
-		default:
-			occupancyStatus = 0 // NO_DATA_AVAILABLE
+			occupancyStatus = gtfs.VehiclePosition_FULL
 		}
 		vp.OccupancyStatus = &occupancyStatus
 	}
@@ -82,55 +92,43 @@ func MapVMToVehiclePosition(va *siri.VehicleActivity, opts Options) *Entity {
 	// Determine current status based on VehicleAtStop
 	if mvj.MonitoredCall != nil && mvj.MonitoredCall.VehicleAtStop != nil {
 		if *mvj.MonitoredCall.VehicleAtStop {
-			currentStatus := int32(1) // STOPPED_AT
-			vp.CurrentStatus = &currentStatus
+			vp.CurrentStatus = gtfs.VehiclePosition_STOPPED_AT.Enum()
 		} else {
-			currentStatus := int32(2) // IN_TRANSIT_TO
-			vp.CurrentStatus = &currentStatus
+			vp.CurrentStatus = gtfs.VehiclePosition_IN_TRANSIT_TO.Enum()
 		}
 	}
 
 	if mvj.FramedVehicleJourneyRef != nil && mvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef != nil {
 		tripId := stripPrefix(*mvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef, "SOFIA:ServiceJourney:")
-		schedRel := int32(0) // SCHEDULED
-		td := &gtfsrt.TripDescriptor{
-			TripId:               tripId,
-			ScheduleRelationship: &schedRel,
+		td := &gtfs.TripDescriptor{
+			TripId:               &tripId,
+			ScheduleRelationship: gtfs.TripDescriptor_SCHEDULED.Enum(),
 		}
 		if mvj.OriginAimedDepartureTime != nil {
 			if t, ok := siri.ParseISOTime(*mvj.OriginAimedDepartureTime); ok {
-				td.StartDate = siri.FormatDateYYYYMMDD(t)
+				td.StartDate = proto.String(siri.FormatDateYYYYMMDD(t))
 			}
 		}
 		if mvj.LineRef != nil {
-			td.RouteId = stripPrefix(*mvj.LineRef, "SOFIA:Line:")
+			td.RouteId = proto.String(stripPrefix(*mvj.LineRef, "SOFIA:Line:"))
 		}
 		vp.Trip = td
 	}
 
 	// Set stop_id from MonitoredCall
 	if mvj.MonitoredCall != nil && mvj.MonitoredCall.StopPointRef != nil {
-		stopId := stripPrefix(*mvj.MonitoredCall.StopPointRef, "SOFIA:Quay:")
-		vp.StopId = &stopId
+		vp.StopId = proto.String(stripPrefix(*mvj.MonitoredCall.StopPointRef, "SOFIA:Quay:"))
 	}
 	if mvj.VehicleRef != nil && *mvj.VehicleRef != "" {
-		vp.Vehicle = &gtfsrt.VehicleDescriptor{Id: stripPrefix(*mvj.VehicleRef, "SOFIA:VehicleRef:")}
-	}
-	if mvj.VehicleLocation != nil {
-		pos := &gtfsrt.Position{Latitude: float32(mvj.VehicleLocation.Latitude), Longitude: float32(mvj.VehicleLocation.Longitude)}
-		if mvj.Bearing != nil {
-			pos.Bearing = mvj.Bearing
-		}
-		if mvj.Velocity != nil {
-			sp := float32(*mvj.Velocity)
-			pos.Speed = &sp
-		}
-		vp.Position = pos
+		vp.Vehicle = &gtfs.VehicleDescriptor{Id: proto.String(stripPrefix(*mvj.VehicleRef, "SOFIA:VehicleRef:"))}
 	}
+	vp.Position = pos
 	if va.RecordedAtTime != nil {
 		if t, ok := siri.ParseISOTime(*va.RecordedAtTime); ok {
-			ts := fmt.Sprintf("%d", t.Unix())
-			vp.Timestamp = &ts
+			vp.Timestamp = proto.Uint64(uint64(t.Unix()))
+			if opts.VehicleHistory != nil && vp.Vehicle != nil {
+				opts.VehicleHistory.derive(vp.Vehicle.GetId(), t, mvj.VehicleLocation.Latitude, mvj.VehicleLocation.Longitude, pos)
+			}
 		}
 	}
 	ent.Vehicle = vp
@@ -180,110 +178,90 @@ func MapETToTripUpdate(evj *siri.EstimatedVehicleJourney, opts Options) *Entity
 	}
 	ttl := opts.VMGracePeriod
 	if !latest.IsZero() {
-		d := time.Until(latest)
+		d := latest.Sub(opts.now())
 		if d > 0 {
 			ttl = d
 		}
 	}
 
-	isDeleted := false
-	ent := &gtfsrt.FeedEntity{Id: &id, IsDeleted: &isDeleted}
-	tu := &gtfsrt.TripUpdate{}
+	ent := &gtfs.FeedEntity{Id: &id, IsDeleted: proto.Bool(false)}
+	tu := &gtfs.TripUpdate{}
 
 	// Set timestamp from RecordedAtTime
 	if evj.RecordedAtTime != nil {
 		if t, ok := siri.ParseISOTime(*evj.RecordedAtTime); ok {
-			tu.Timestamp = fmt.Sprintf("%d", t.Unix())
+			tu.Timestamp = proto.Uint64(uint64(t.Unix()))
 		}
 	}
 
-	schedRel := int32(0) // SCHEDULED
-	td := &gtfsrt.TripDescriptor{
-		TripId:               tripId,
+	schedRel := gtfs.TripDescriptor_SCHEDULED
+	if opts.ETCancellations && evj.Cancellation != nil && *evj.Cancellation {
+		schedRel = gtfs.TripDescriptor_CANCELED
+	} else if evj.ExtraJourney != nil && *evj.ExtraJourney {
+		schedRel = gtfs.TripDescriptor_ADDED
+	}
+	td := &gtfs.TripDescriptor{
+		TripId:               &tripId,
 		ScheduleRelationship: &schedRel,
 	}
 	if evj.LineRef != nil {
-		td.RouteId = stripPrefix(*evj.LineRef, "SOFIA:Line:")
+		td.RouteId = proto.String(stripPrefix(*evj.LineRef, "SOFIA:Line:"))
 	}
 	if evj.OriginAimedDepartureTime != nil {
 		if t, ok := siri.ParseISOTime(*evj.OriginAimedDepartureTime); ok {
-			td.StartDate = siri.FormatDateYYYYMMDD(t)
-			td.StartTime = t.Format("15:04:05")
+			td.StartDate = proto.String(siri.FormatDateYYYYMMDD(t))
+			td.StartTime = proto.String(t.Format("15:04:05"))
 		}
 	}
+	if dir, ok := directionID(derefString(evj.DirectionRef)); ok {
+		td.DirectionId = &dir
+	}
 	tu.Trip = td
 	if evj.VehicleRef != nil && *evj.VehicleRef != "" {
-		tu.Vehicle = &gtfsrt.VehicleDescriptor{Id: stripPrefix(*evj.VehicleRef, "SOFIA:VehicleRef:")}
+		tu.Vehicle = &gtfs.VehicleDescriptor{Id: proto.String(stripPrefix(*evj.VehicleRef, "SOFIA:VehicleRef:"))}
+	}
+	if pj := opts.Timetable.lookup(tripId, startDate); pj != nil {
+		pj.apply(tu)
 	}
 
-	stopSeq := int32(0)
-	schedRel0 := int32(0) // SCHEDULED
-	uncertainty0 := int32(0)
+	stopSeq := uint32(0)
 
 	for _, rc := range evj.RecordedCalls {
-		stu := gtfsrt.StopTimeUpdate{ScheduleRelationship: &schedRel0}
+		stu := &gtfs.TripUpdate_StopTimeUpdate{ScheduleRelationship: gtfs.TripUpdate_StopTimeUpdate_SCHEDULED.Enum()}
 		if rc.StopPointRef != nil {
-			stu.StopId = stripPrefix(*rc.StopPointRef, "SOFIA:Quay:")
-		}
-		if rc.Order != nil && *rc.Order > 0 {
-			stu.StopSequence = *rc.Order - 1
-		} else {
-			stu.StopSequence = stopSeq
+			stu.StopId = proto.String(stripPrefix(*rc.StopPointRef, "SOFIA:Quay:"))
 		}
+		stu.StopSequence = stopSequence(rc.Order, stopSeq)
 
 		// Use absolute time (actual or expected) instead of delay
 		if rc.ActualArrivalTime != nil {
-			if t, ok := siri.ParseISOTime(*rc.ActualArrivalTime); ok {
-				ts := fmt.Sprintf("%d", t.Unix())
-				stu.Arrival = &gtfsrt.StopTimeEvent{Time: ts, Uncertainty: &uncertainty0}
-			}
-		} else if rc.ExpectedArrivalTime != nil {
-			if t, ok := siri.ParseISOTime(*rc.ExpectedArrivalTime); ok {
-				ts := fmt.Sprintf("%d", t.Unix())
-				stu.Arrival = &gtfsrt.StopTimeEvent{Time: ts, Uncertainty: &uncertainty0}
-			}
+			stu.Arrival = stopTimeEvent(rc.ActualArrivalTime)
+		} else {
+			stu.Arrival = stopTimeEvent(rc.ExpectedArrivalTime)
 		}
 
 		if rc.ActualDepartureTime != nil {
-			if t, ok := siri.ParseISOTime(*rc.ActualDepartureTime); ok {
-				ts := fmt.Sprintf("%d", t.Unix())
-				stu.Departure = &gtfsrt.StopTimeEvent{Time: ts, Uncertainty: &uncertainty0}
-			}
-		} else if rc.ExpectedDepartureTime != nil {
-			if t, ok := siri.ParseISOTime(*rc.ExpectedDepartureTime); ok {
-				ts := fmt.Sprintf("%d", t.Unix())
-				stu.Departure = &gtfsrt.StopTimeEvent{Time: ts, Uncertainty: &uncertainty0}
-			}
+			stu.Departure = stopTimeEvent(rc.ActualDepartureTime)
+		} else {
+			stu.Departure = stopTimeEvent(rc.ExpectedDepartureTime)
 		}
 
 		tu.StopTimeUpdate = append(tu.StopTimeUpdate, stu)
 		stopSeq++
 	}
 	for _, ec := range evj.EstimatedCalls {
-		stu := gtfsrt.StopTimeUpdate{ScheduleRelationship: &schedRel0}
-		if ec.StopPointRef != nil {
-			stu.StopId = stripPrefix(*ec.StopPointRef, "SOFIA:Quay:")
+		stu := &gtfs.TripUpdate_StopTimeUpdate{ScheduleRelationship: gtfs.TripUpdate_StopTimeUpdate_SCHEDULED.Enum()}
+		if opts.ETCancellations && ec.Cancellation != nil && *ec.Cancellation {
+			stu.ScheduleRelationship = gtfs.TripUpdate_StopTimeUpdate_SKIPPED.Enum()
 		}
-		if ec.Order != nil && *ec.Order > 0 {
-			stu.StopSequence = *ec.Order - 1
-		} else {
-			stu.StopSequence = stopSeq
+		if ec.StopPointRef != nil {
+			stu.StopId = proto.String(stripPrefix(*ec.StopPointRef, "SOFIA:Quay:"))
 		}
+		stu.StopSequence = stopSequence(ec.Order, stopSeq)
 
 		// Use expected time for estimated calls
-		if ec.ExpectedArrivalTime != nil {
-			if t, ok := siri.ParseISOTime(*ec.ExpectedArrivalTime); ok {
-				ts := fmt.Sprintf("%d", t.Unix())
-				stu.Arrival = &gtfsrt.StopTimeEvent{Time: ts, Uncertainty: &uncertainty0}
-			}
-		}
-
-		if ec.ExpectedDepartureTime != nil {
-			if t, ok := siri.ParseISOTime(*ec.ExpectedDepartureTime); ok {
-				ts := fmt.Sprintf("%d", t.Unix())
-				stu.Departure = &gtfsrt.StopTimeEvent{Time: ts, Uncertainty: &uncertainty0}
-			}
-		}
+		stu.Arrival = stopTimeEvent(ec.ExpectedArrivalTime)
+		stu.Departure = stopTimeEvent(ec.ExpectedDepartureTime)
 
 		tu.StopTimeUpdate = append(tu.StopTimeUpdate, stu)
 		stopSeq++
@@ -307,25 +285,27 @@ func MapSXToAlert(sx *siri.PtSituationElement, opts Options) *Entity {
 	// Strip SOFIA:SituationNumber: prefix
 	id := stripPrefix(*sx.SituationNumber, "SOFIA:SituationNumber:")
 
-	var end time.Time
-	for _, vp := range sx.ValidityPeriods {
-		if vp.EndTime != nil {
-			if t, ok := siri.ParseISOTime(*vp.EndTime); ok {
-				if t.After(end) {
-					end = t
-				}
-			}
-		}
+	if opts.SituationVersions != nil && !opts.SituationVersions.accept(id, situationVersionOf(sx, opts.now())) {
+		opts.reject("alert", id, RejectOutdatedVersion)
+		return nil
 	}
-	ttl := 365 * 24 * time.Hour
-	if !end.IsZero() {
-		if d := time.Until(end); d > 0 {
-			ttl = d
+	if isClosed(sx) {
+		if opts.SXClosedAsDeleted {
+			return deletedAlert(id, sx, opts)
 		}
+		opts.reject("alert", id, RejectClosed)
+		return nil
+	}
+
+	now := opts.now()
+	if reason := checkPublication(sx, now, opts); reason != "" {
+		opts.reject("alert", id, reason)
+		return nil
 	}
+	ttl := alertTTL(sx, now, opts)
 
-	ent := &gtfsrt.FeedEntity{Id: &id}
-	alert := &gtfsrt.Alert{}
+	ent := &gtfs.FeedEntity{Id: &id}
+	alert := &gtfs.Alert{}
 
 	// Parse cause and effect from Summary text
 	var bgSummary, enSummary string
@@ -350,28 +330,18 @@ func MapSXToAlert(sx *siri.PtSituationElement, opts Options) *Entity {
 	}
 
 	if summaryForParsing != "" {
-		cause := parseCauseFromSummary(summaryForParsing)
-		alert.Cause = &cause
-		effect := parseEffectFromSummary(summaryForParsing)
-		alert.Effect = &effect
+		alert.Cause = parseCauseFromSummary(summaryForParsing).Enum()
+		alert.Effect = parseEffectFromSummary(summaryForParsing).Enum()
 	} else {
 		// Default values if no summary
-		defaultCause := int32(1)  // OTHER_CAUSE
-		defaultEffect := int32(8) // OTHER_EFFECT
-		alert.Cause = &defaultCause
-		alert.Effect = &defaultEffect
+		alert.Cause = gtfs.Alert_UNKNOWN_CAUSE.Enum()
+		alert.Effect = gtfs.Alert_UNKNOWN_EFFECT.Enum()
 	}
 
 	if sx.Severity != nil {
-		s := *sx.Severity
-		alert.Severity = &s
-	}
-	alert.HeaderText = &gtfsrt.TranslatedString{
-		Translation: []gtfsrt.Translation{
-			{Text: bgSummary, Language: strPtr("bg")},
-			{Text: enSummary, Language: strPtr("en")},
-		},
+		alert.SeverityLevel = alertSeverity(*sx.Severity)
 	}
+	alert.HeaderText = bgEnString(bgSummary, enSummary)
 	var bgDescription, enDescription string
 	if len(sx.Descriptions) > 0 {
 		for _, t := range sx.Descriptions {
@@ -386,106 +356,12 @@ func MapSXToAlert(sx *siri.PtSituationElement, opts Options) *Entity {
 			}
 		}
 	}
-	alert.DescriptionText = &gtfsrt.TranslatedString{
-		Translation: []gtfsrt.Translation{
-			{Text: bgDescription, Language: strPtr("bg")},
-			{Text: enDescription, Language: strPtr("en")},
-		},
-	}
-	// Prefer PublicationWindow if present; else fallback to ValidityPeriods
-	if sx.PublicationWindow != nil && (sx.PublicationWindow.StartTime != nil || sx.PublicationWindow.EndTime != nil) {
-		tr := gtfsrt.TimeRange{}
-		if sx.PublicationWindow.StartTime != nil {
-			if t, ok := siri.ParseISOTime(*sx.PublicationWindow.StartTime); ok {
-				ts := t.Unix()
-				tr.Start = &ts
-			}
-		}
-		if sx.PublicationWindow.EndTime != nil {
-			if t, ok := siri.ParseISOTime(*sx.PublicationWindow.EndTime); ok {
-				te := t.Unix()
-				tr.End = &te
-			}
-		}
-		alert.ActivePeriod = append(alert.ActivePeriod, tr)
-	} else {
-		for _, vp := range sx.ValidityPeriods {
-			tr := gtfsrt.TimeRange{}
-			if vp.StartTime != nil {
-				if t, ok := siri.ParseISOTime(*vp.StartTime); ok {
-					ts := t.Unix()
-					tr.Start = &ts
-				}
-			}
-			if vp.EndTime != nil {
-				if t, ok := siri.ParseISOTime(*vp.EndTime); ok {
-					te := t.Unix()
-					tr.End = &te
-				}
-			}
-			alert.ActivePeriod = append(alert.ActivePeriod, tr)
-		}
-	}
-	if sx.Affects != nil {
-		for _, sp := range sx.Affects.StopPoints {
-			if sp.StopPointRef != nil {
-				sid := stripPrefix(*sp.StopPointRef, "SOFIA:Quay:")
-				alert.InformedEntity = append(alert.InformedEntity, gtfsrt.EntitySelector{StopId: &sid})
-			}
-		}
-		for _, vj := range sx.Affects.VehicleJourneys {
-			if vj.LineRef != nil {
-				rid := stripPrefix(*vj.LineRef, "SOFIA:Line:")
-				alert.InformedEntity = append(alert.InformedEntity, gtfsrt.EntitySelector{RouteId: &rid})
-			}
-			if vj.FramedVehicleJourneyRef != nil && vj.FramedVehicleJourneyRef.DatedVehicleJourneyRef != nil {
-				tid := stripPrefix(*vj.FramedVehicleJourneyRef.DatedVehicleJourneyRef, "SOFIA:ServiceJourney:")
-				td := gtfsrt.TripDescriptor{TripId: tid}
-				if vj.FramedVehicleJourneyRef.DataFrameRef != nil {
-					td.StartDate = sanitizeDate(*vj.FramedVehicleJourneyRef.DataFrameRef)
-				}
-				alert.InformedEntity = append(alert.InformedEntity, gtfsrt.EntitySelector{Trip: &td})
-			}
-			for _, dvj := range vj.DatedVehicleJourneyRefs {
-				tid := stripPrefix(dvj, "SOFIA:ServiceJourney:")
-				td := gtfsrt.TripDescriptor{TripId: tid}
-				if vj.OriginAimedDepartureTime != nil {
-					if t, ok := siri.ParseISOTime(*vj.OriginAimedDepartureTime); ok {
-						td.StartDate = siri.FormatDateYYYYMMDD(t)
-					}
-				}
-				alert.InformedEntity = append(alert.InformedEntity, gtfsrt.EntitySelector{Trip: &td})
-			}
-			for _, r := range vj.Routes {
-				for _, sp := range r.StopPoints.StopPoints {
-					if sp.StopPointRef != nil {
-						sid := stripPrefix(*sp.StopPointRef, "SOFIA:Quay:")
-						alert.InformedEntity = append(alert.InformedEntity, gtfsrt.EntitySelector{StopId: &sid})
-					}
-				}
-			}
-		}
-		for _, net := range sx.Affects.Networks {
-			for _, line := range net.AffectedLines {
-				if line.LineRef != nil {
-					rid := stripPrefix(*line.LineRef, "SOFIA:Line:")
-					alert.InformedEntity = append(alert.InformedEntity, gtfsrt.EntitySelector{RouteId: &rid})
-				}
-				for _, r := range line.Routes {
-					for _, sp := range r.StopPoints.StopPoints {
-						if sp.StopPointRef != nil {
-							sid := stripPrefix(*sp.StopPointRef, "SOFIA:Quay:")
-							ridPtr := line.LineRef
-							if ridPtr != nil {
-								rid := stripPrefix(*ridPtr, "SOFIA:Line:")
-								alert.InformedEntity = append(alert.InformedEntity, gtfsrt.EntitySelector{RouteId: &rid, StopId: &sid})
-							} else {
-								alert.InformedEntity = append(alert.InformedEntity, gtfsrt.EntitySelector{StopId: &sid})
-							}
-						}
-					}
-				}
-			}
+	alert.DescriptionText = bgEnString(bgDescription, enDescription)
+	alert.ActivePeriod = activePeriods(sx)
+	if affects := alertAffects(sx, opts); affects != nil {
+		alert.InformedEntity = mapInformedEntities(affects, situationDate(sx), opts)
+		if hasAccessibilityIssue(affects) && (alert.GetEffect() == gtfs.Alert_OTHER_EFFECT || alert.GetEffect() == gtfs.Alert_UNKNOWN_EFFECT) {
+			alert.Effect = gtfs.Alert_ACCESSIBILITY_ISSUE.Enum()
 		}
 	}
 	var bgUrl, enUrl string
@@ -496,18 +372,15 @@ func MapSXToAlert(sx *siri.PtSituationElement, opts Options) *Entity {
 				lang = "en" // Default to English if no language specified
 			}
 			if lang == "bg" {
-				bgUrl = l.Uri
+				bgUrl = infoLinkURL(l)
 			} else if lang == "en" {
-				enUrl = l.Uri
+				enUrl = infoLinkURL(l)
 			}
 		}
 	}
-	alert.Url = &gtfsrt.TranslatedString{
-		Translation: []gtfsrt.Translation{
-			{Text: bgUrl, Language: strPtr("bg")},
-			{Text: enUrl, Language: strPtr("en")},
-		},
-	}
+	alert.Url = bgEnString(bgUrl, enUrl)
+
+	mapAlertTexts(sx, alert, opts)
 
 	ent.Alert = alert
 	return &Entity{ID: id, Datasource: derefString(sx.ParticipantRef), Message: ent, TTL: ttl}
@@ -532,6 +405,32 @@ func strPtr(s string) *string {
 	return &s
 }
 
+// stopSequence is the zero-based GTFS stop_sequence of a SIRI call Order,
+// or fallback when the call has none.
+func stopSequence(order *int32, fallback uint32) *uint32 {
+	if order != nil && *order > 0 {
+		return proto.Uint32(uint32(*order - 1))
+	}
+	return proto.Uint32(fallback)
+}
+
+// alertSeverity maps SIRI Severity (case-insensitive) to severity_level; nil
+// when the SIRI value carries no severity information.
+func alertSeverity(s string) *gtfs.Alert_SeverityLevel {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "noimpact", "veryslight", "slight":
+		return gtfs.Alert_INFO.Enum()
+	case "normal":
+		return gtfs.Alert_WARNING.Enum()
+	case "severe", "verysevere":
+		return gtfs.Alert_SEVERE.Enum()
+	case "unknown":
+		return gtfs.Alert_UNKNOWN_SEVERITY.Enum()
+	default:
+		return nil
+	}
+}
+
 func stripPrefix(s, prefix string) string {
 	if len(s) > len(prefix) && s[:len(prefix)] == prefix {
 		return s[len(prefix):]
@@ -599,7 +498,7 @@ func mapEffectIntToString(effect int32) string {
 	}
 }
 
-func parseCauseFromSummary(summary string) int32 {
+func parseCauseFromSummary(summary string) gtfs.Alert_Cause {
 	// Extract cause part from "X:Y" format (e.g., "Maintenance:Stop moved")
 	s := strings.ToLower(summary)
 
@@ -610,46 +509,46 @@ func parseCauseFromSummary(summary string) int32 {
 	}
 
 	if strings.Contains(causePart, "maintenance") || strings.Contains(causePart, "поддръжка") {
-		return 9 // MAINTENANCE
+		return gtfs.Alert_MAINTENANCE
 	}
 	if strings.Contains(causePart, "construction") || strings.Contains(causePart, "строителна") {
-		return 10 // CONSTRUCTION
+		return gtfs.Alert_CONSTRUCTION
 	}
 	if strings.Contains(causePart, "technical problem") || strings.Contains(causePart, "технически проблем") {
-		return 3 // TECHNICAL_PROBLEM
+		return gtfs.Alert_TECHNICAL_PROBLEM
 	}
 	if strings.Contains(causePart, "strike") || strings.Contains(causePart, "стачка") {
-		return 4 // STRIKE
+		return gtfs.Alert_STRIKE
 	}
 	if strings.Contains(causePart, "demonstration") || strings.Contains(causePart, "демонстрация") {
-		return 5 // DEMONSTRATION
+		return gtfs.Alert_DEMONSTRATION
 	}
 	if strings.Contains(causePart, "accident") || strings.Contains(causePart, "авария") {
-		return 6 // ACCIDENT
+		return gtfs.Alert_ACCIDENT
 	}
 	if strings.Contains(causePart, "holiday") || strings.Contains(causePart, "праздник") {
-		return 7 // HOLIDAY
+		return gtfs.Alert_HOLIDAY
 	}
 	if strings.Contains(causePart, "weather") || strings.Contains(causePart, "време") {
-		return 8 // WEATHER
+		return gtfs.Alert_WEATHER
 	}
 	if strings.Contains(causePart, "police") || strings.Contains(causePart, "полиц") {
-		return 11 // POLICE_ACTIVITY
+		return gtfs.Alert_POLICE_ACTIVITY
 	}
 	if strings.Contains(causePart, "medical") || strings.Contains(causePart, "медицин") {
-		return 12 // MEDICAL_EMERGENCY
+		return gtfs.Alert_MEDICAL_EMERGENCY
 	}
 	if strings.Contains(causePart, "unknown") || strings.Contains(causePart, "неизвестно") {
-		return 1 // UNKNOWN_CAUSE
+		return gtfs.Alert_UNKNOWN_CAUSE
 	}
 	if strings.Contains(causePart, "other") || strings.Contains(causePart, "друго") {
-		return 2 // OTHER_CAUSE
+		return gtfs.Alert_OTHER_CAUSE
 	}
 
-	return 2 // OTHER_CAUSE (default)
+	return gtfs.Alert_OTHER_CAUSE // default
 }
 
-func parseEffectFromSummary(summary string) int32 {
+func parseEffectFromSummary(summary string) gtfs.Alert_Effect {
 	// Extract effect part from "X:Y" format (e.g., "Maintenance:Stop moved")
 	s := strings.ToLower(summary)
 
@@ -660,47 +559,59 @@ func parseEffectFromSummary(summary string) int32 {
 	}
 
 	if strings.Contains(effectPart, "no service") || strings.Contains(effectPart, "не се изпълнява") {
-		return 1 // NO_SERVICE
+		return gtfs.Alert_NO_SERVICE
 	}
 	if strings.Contains(effectPart, "reduced service") || strings.Contains(effectPart, "понижено обслужване") {
-		return 2 // REDUCED_SERVICE
+		return gtfs.Alert_REDUCED_SERVICE
 	}
 	if strings.Contains(effectPart, "significant delay") || strings.Contains(effectPart, "значителни закъснения") {
-		return 3 // SIGNIFICANT_DELAYS
+		return gtfs.Alert_SIGNIFICANT_DELAYS
 	}
 	if strings.Contains(effectPart, "detour") || strings.Contains(effectPart, "отклонение") {
-		return 4 // DETOUR
+		return gtfs.Alert_DETOUR
 	}
 	if strings.Contains(effectPart, "additional service") || strings.Contains(effectPart, "допълнително обслужване") {
-		return 5 // ADDITIONAL_SERVICE
+		return gtfs.Alert_ADDITIONAL_SERVICE
 	}
 	if strings.Contains(effectPart, "modified service") || strings.Contains(effectPart, "модифицирано обслужване") {
-		return 6 // MODIFIED_SERVICE
+		return gtfs.Alert_MODIFIED_SERVICE
 	}
 	if strings.Contains(effectPart, "stop moved") || strings.Contains(effectPart, "преместена спирка") {
-		return 9 // STOP_MOVED
+		return gtfs.Alert_STOP_MOVED
 	}
 	if strings.Contains(effectPart, "no impact") || strings.Contains(effectPart, "no effect") || strings.Contains(effectPart, "няма ефект") {
-		return 10 // NO_EFFECT
+		return gtfs.Alert_NO_EFFECT
 	}
 	if strings.Contains(effectPart, "accessibility") || strings.Contains(effectPart, "достъпност") {
-		return 11 // ACCESSIBILITY_ISSUE
+		return gtfs.Alert_ACCESSIBILITY_ISSUE
 	}
 	if strings.Contains(effectPart, "unknown") || strings.Contains(effectPart, "неизвестно") {
-		return 8 // UNKNOWN_EFFECT
+		return gtfs.Alert_UNKNOWN_EFFECT
 	}
 	if strings.Contains(effectPart, "other") || strings.Contains(effectPart, "друго") {
-		return 7 // OTHER_EFFECT
+		return gtfs.Alert_OTHER_EFFECT
 	}
 
-	return 7 // OTHER_EFFECT (default)
+	return gtfs.Alert_OTHER_EFFECT // default
 }
 
-func sanitizeDate(s string) string {
-	if len(s) == 10 && s[4] == '-' && s[7] == '-' {
-		return s[0:4] + s[5:7] + s[8:10]
+// dataFrameDate matches a YYYY-MM-DD or YYYYMMDD date not surrounded by other
+// digits, e.g. "2024-01-01", "20240101" or "SOFIA:DataFrame:2024-01-01".
+var dataFrameDate = regexp.MustCompile(`(?:^|\D)(\d{4})-?(\d{2})-?(\d{2})(?:\D|$)`)
+
+// sanitizeDate extracts a GTFS start_date (YYYYMMDD) from a DataFrameRef and
+// reports whether a valid calendar date was found.
+func sanitizeDate(s string) (string, bool) {
+	m := dataFrameDate.FindAllStringSubmatch(strings.TrimSpace(s), -1)
+	if len(m) == 0 {
+		return "", false
 	}
-	return s
+	last := m[len(m)-1]
+	d := last[1] + last[2] + last[3]
+	if _, err := time.Parse("20060102", d); err != nil {
+		return "", false
+	}
+	return d, true
 }
 
 func delaySeconds(aimed, actual, expected *string) *int32 {
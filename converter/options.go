@@ -3,16 +3,20 @@ package converter
 import (
 	"time"
 
-	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
 )
 
-// Entity is a GTFS-RT entity with metadata and TTL semantics.
+// Entity is a GTFS-RT entity with metadata and TTL semantics. Message is
+// the MobilityData protobuf entity; the JSON view is derived from it.
 type Entity struct {
 	ID         string
 	Datasource string
 	Kind       string // "trip_update" | "vehicle_position" | "alert"
-	Message    *gtfsrt.FeedEntity
+	Message    *gtfs.FeedEntity
 	TTL        time.Duration
+	// Deleted marks an is_deleted tombstone. Tombstones are only published
+	// in DIFFERENTIAL feeds; full-dataset builders skip them.
+	Deleted bool
 }
 
 // Options controls conversion behavior and filters.
@@ -25,6 +29,89 @@ type Options struct {
 	CloseToNextStopDistance   int
 
 	VMGracePeriod time.Duration
+
+	// ETCancellations maps cancelled ET journeys to CANCELED trip updates and
+	// cancelled calls to SKIPPED stop time updates.
+	ETCancellations bool
+
+	// Vehicle position validation
+	BoundingBox      *BoundingBox // drop positions outside this area (nil disables)
+	DropNullIsland   bool         // drop positions at exactly (0,0)
+	NormalizeBearing bool         // wrap bearings into [0,360) instead of dropping them
+	VelocityUnit     SpeedUnit    // unit of SIRI Velocity; converted to m/s
+
+	// Vehicle staleness policy (zero values disable each check)
+	VMMaxAge            time.Duration // drop activities whose RecordedAtTime is older than this
+	VMFutureTolerance   time.Duration // drop activities recorded further than this in the future
+	DropExpiredVehicles bool          // drop activities whose ValidUntilTime has passed
+
+	// Workers is the number of goroutines mapping ET journeys in
+	// ConvertSIRI; 0 or 1 maps them sequentially. With more workers, Now and
+	// Timetable are used concurrently.
+	Workers int
+
+	// Now returns the current time; defaults to time.Now. Inject a fixed
+	// clock for replaying recorded feeds or in tests.
+	Now func() time.Time
+
+	// VMEntityID selects how vehicle position entity IDs are built.
+	VMEntityID EntityIDStrategy
+	// VMOneEntityPerVehicle keeps only the newest position per vehicle when a
+	// vehicle is reported on several journeys (block continuation).
+	VMOneEntityPerVehicle bool
+
+	// VehicleHistory, when set, derives missing bearing and speed from
+	// successive samples of the same VehicleRef. ConvertSIRI prunes samples
+	// older than its MaxSampleAge.
+	VehicleHistory *VehicleHistory
+
+	// Stops, when set, expands SX StopPlace references to the child stops of
+	// the matching static GTFS station.
+	Stops *StopIndex
+
+	// Timetable, when set, completes ET trip updates with SIRI-PT route,
+	// direction and headsign and marks extra journeys as ADDED. ConvertSIRI
+	// forgets journeys finished longer ago than its Retention.
+	Timetable *Timetable
+	// PTEmitExtraJourneys publishes planned extra journeys from the
+	// timetable as ADDED trip updates when ET does not report them.
+	PTEmitExtraJourneys bool
+
+	// Interchanges maps SIRI-CM InterchangeRefs to guaranteed connections so
+	// that late feeders hold the distributor departure.
+	Interchanges map[string]Interchange
+
+	// SituationVersions, when set, keeps older situation versions from
+	// overwriting newer ones across conversions. ConvertSIRI forgets
+	// situations not seen for its Retention.
+	SituationVersions *SituationVersions
+	// SXClosedAsDeleted emits closed situations (Progress=closed) as
+	// Deleted tombstones for a Differential instead of dropping them.
+	SXClosedAsDeleted bool
+
+	// SX publication filtering
+	SXHideBeforePublication bool          // drop situations whose PublicationWindow has not started
+	SXDropAfterPublication  bool          // drop situations whose PublicationWindow (or last ValidityPeriod) has ended
+	SXDefaultTTL            time.Duration // TTL for open-ended situations (365 days in DefaultOptions)
+
+	// SX texts
+	SXComposeDescription bool // append Detail, Advice, consequence advice and InfoLink labels to description_text
+	SXTextToSpeech       bool // also emit tts_header_text and tts_description_text
+
+	// SX PublishingActions (SIRI 2.x / Nordic profile)
+	SXUsePublishAtScope       bool // inform the PublishAtScope affects instead of the top-level Affects
+	SXUsePassengerInformation bool // take header/description from PassengerInformationAction texts
+
+	// SyntheticAlerts, when set, derives alerts for cancelled or heavily
+	// delayed journeys that no SX situation covers.
+	SyntheticAlerts *SyntheticAlerts
+
+	// NetworkAgencyID is informed for network-wide SX situations (AllLines
+	// without operator or vehicle mode).
+	NetworkAgencyID string
+
+	// OnReject, when set, is called for every record dropped by validation.
+	OnReject func(Rejection)
 }
 
 func DefaultOptions() Options {
@@ -32,5 +119,16 @@ func DefaultOptions() Options {
 		CloseToNextStopPercentage: 95,
 		CloseToNextStopDistance:   500,
 		VMGracePeriod:             5 * time.Minute,
+		SXDefaultTTL:              365 * 24 * time.Hour,
+		DropNullIsland:            true,
+		NormalizeBearing:          true,
+		VelocityUnit:              SpeedMetersPerSecond,
+	}
+}
+
+func (o Options) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
 	}
+	return time.Now()
 }
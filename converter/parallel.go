@@ -0,0 +1,44 @@
+package converter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// forEachIndex calls fn for every index in [0, n). With more than one worker
+// the calls run on a bounded pool of goroutines; fn must only write to state
+// owned by its index. It stops handing out indexes once ctx is done and
+// returns ctx.Err().
+func forEachIndex(ctx context.Context, n, workers int, fn func(i int)) error {
+	if workers <= 1 || n < 2 {
+		for i := 0; i < n; i++ {
+			if i%256 == 0 && ctx.Err() != nil {
+				break
+			}
+			fn(i)
+		}
+		return ctx.Err()
+	}
+	if workers > n {
+		workers = n
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
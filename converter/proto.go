@@ -0,0 +1,62 @@
+package converter
+
+import (
+	"context"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// Protobuf-native output
+
+// ConvertSIRIToProto converts a ServiceDelivery into a MobilityData GTFS-RT
+// protobuf FeedMessage, for callers that serve PBF or work with the
+// bindings. Entities are built as protobuf messages, so every field the
+// mappers set is kept; fields newer than the bindings travel as unknown
+// fields. Use gtfsrt.MarshalProtoJSON for its JSON view.
+func ConvertSIRIToProto(sd *siri.ServiceDelivery, opts Options) (*gtfs.FeedMessage, error) {
+	return ConvertSIRIToProtoContext(context.Background(), sd, opts)
+}
+
+// ConvertSIRIToProtoContext is ConvertSIRIToProto with cancellation.
+func ConvertSIRIToProtoContext(ctx context.Context, sd *siri.ServiceDelivery, opts Options) (*gtfs.FeedMessage, error) {
+	entities, err := convertSIRI(ctx, sd, opts)
+	if err != nil {
+		return nil, err
+	}
+	return BuildProtoFeedMessage(entities), nil
+}
+
+// BuildProtoFeedMessage is BuildFeedMessage producing the protobuf message.
+func BuildProtoFeedMessage(entities []Entity) *gtfs.FeedMessage {
+	pm := newProtoFeedMessage()
+	for _, e := range entities {
+		if e.Message != nil && !e.Deleted {
+			pm.Entity = append(pm.Entity, e.Message)
+		}
+	}
+	return pm
+}
+
+// BuildProtoPerDatasource is BuildPerDatasource producing protobuf messages.
+func BuildProtoPerDatasource(entities []Entity) map[string]*gtfs.FeedMessage {
+	out := make(map[string]*gtfs.FeedMessage)
+	for _, e := range entities {
+		if e.Message == nil || e.Deleted {
+			continue
+		}
+		pm, ok := out[e.Datasource]
+		if !ok {
+			pm = newProtoFeedMessage()
+			out[e.Datasource] = pm
+		}
+		pm.Entity = append(pm.Entity, e.Message)
+	}
+	return out
+}
+
+func newProtoFeedMessage() *gtfs.FeedMessage {
+	return gtfsrt.ToProto(&gtfsrt.FeedMessage{Header: gtfsrt.NewFeedMessageHeader()})
+}
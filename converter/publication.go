@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// SX PublicationWindow / ValidityPeriod handling
+
+// checkPublication applies the publication filters and returns the rejection
+// reason, or "" when the situation should be published now.
+func checkPublication(sx *siri.PtSituationElement, now time.Time, opts Options) string {
+	start, end := publicationBounds(sx)
+	if opts.SXHideBeforePublication && !start.IsZero() && now.Before(start) {
+		return RejectNotYetPublished
+	}
+	if opts.SXDropAfterPublication {
+		if end.IsZero() {
+			end = validityEnd(sx)
+		}
+		if !end.IsZero() && !now.Before(end) {
+			return RejectPublicationEnded
+		}
+	}
+	return ""
+}
+
+// alertTTL keeps the alert until the publication window closes, or the last
+// validity period ends when no publication end is given. Open-ended
+// situations get SXDefaultTTL; ended ones only the VM grace period.
+func alertTTL(sx *siri.PtSituationElement, now time.Time, opts Options) time.Duration {
+	_, end := publicationBounds(sx)
+	if end.IsZero() {
+		end = validityEnd(sx)
+	}
+	if end.IsZero() {
+		return opts.SXDefaultTTL
+	}
+	if d := end.Sub(now); d > 0 {
+		return d
+	}
+	return opts.VMGracePeriod
+}
+
+// activePeriods maps the ValidityPeriods to GTFS-RT active_period. The
+// PublicationWindow only decides whether the alert is published at all.
+func activePeriods(sx *siri.PtSituationElement) []*gtfs.TimeRange {
+	var out []*gtfs.TimeRange
+	for _, vp := range sx.ValidityPeriods {
+		out = append(out, timeRange(vp.StartTime, vp.EndTime))
+	}
+	return out
+}
+
+func timeRange(start, end *string) *gtfs.TimeRange {
+	tr := &gtfs.TimeRange{}
+	if start != nil {
+		if t, ok := siri.ParseISOTime(*start); ok {
+			tr.Start = proto.Uint64(uint64(t.Unix()))
+		}
+	}
+	if end != nil {
+		if t, ok := siri.ParseISOTime(*end); ok {
+			tr.End = proto.Uint64(uint64(t.Unix()))
+		}
+	}
+	return tr
+}
+
+func publicationBounds(sx *siri.PtSituationElement) (start, end time.Time) {
+	if sx.PublicationWindow == nil {
+		return
+	}
+	if sx.PublicationWindow.StartTime != nil {
+		start, _ = siri.ParseISOTime(*sx.PublicationWindow.StartTime)
+	}
+	if sx.PublicationWindow.EndTime != nil {
+		end, _ = siri.ParseISOTime(*sx.PublicationWindow.EndTime)
+	}
+	return
+}
+
+// validityEnd returns the latest validity end, or zero if any period is open-ended.
+func validityEnd(sx *siri.PtSituationElement) time.Time {
+	var end time.Time
+	for _, vp := range sx.ValidityPeriods {
+		if vp.EndTime == nil {
+			return time.Time{}
+		}
+		t, ok := siri.ParseISOTime(*vp.EndTime)
+		if !ok {
+			return time.Time{}
+		}
+		end = siri.Latest(end, t)
+	}
+	return end
+}
@@ -0,0 +1,127 @@
+package converter
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// SituationVersions remembers the newest version of every situation so that
+// an older version arriving later never overwrites a newer alert, and a
+// closed situation is not resurrected by a stale "open" copy. Set
+// Options.SituationVersions to share it across conversions. It is safe for
+// concurrent use.
+type SituationVersions struct {
+	// Retention is how long a situation is remembered after it was last
+	// seen; ConvertSIRI prunes older ones. Zero keeps them until Prune.
+	Retention time.Duration
+
+	mu     sync.Mutex
+	latest map[string]situationVersion
+}
+
+type situationVersion struct {
+	version     int64
+	hasVersion  bool
+	versionedAt time.Time
+	closed      bool
+	seenAt      time.Time
+}
+
+// DefaultSituationRetention is how long NewSituationVersions remembers
+// situations that are no longer delivered.
+const DefaultSituationRetention = 24 * time.Hour
+
+// NewSituationVersions returns an empty version tracker with
+// DefaultSituationRetention.
+func NewSituationVersions() *SituationVersions {
+	return &SituationVersions{Retention: DefaultSituationRetention, latest: make(map[string]situationVersion)}
+}
+
+// Len returns the number of situations currently tracked.
+func (s *SituationVersions) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.latest)
+}
+
+// Prune forgets situations last seen before the given time.
+func (s *SituationVersions) Prune(before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, v := range s.latest {
+		if v.seenAt.Before(before) {
+			delete(s.latest, id)
+		}
+	}
+}
+
+// accept records v for id and reports whether it is at least as new as the
+// newest version seen so far.
+func (s *SituationVersions) accept(id string, v situationVersion) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.latest == nil {
+		s.latest = make(map[string]situationVersion)
+	}
+	prev, ok := s.latest[id]
+	if ok && v.olderThan(prev) {
+		return false
+	}
+	if ok && prev.closed && !v.closed && !prev.olderThan(v) {
+		// Same version re-sent without the closing flag; keep it closed.
+		return false
+	}
+	s.latest[id] = v
+	return true
+}
+
+// olderThan compares Version first and falls back to VersionedAtTime.
+func (v situationVersion) olderThan(o situationVersion) bool {
+	if v.hasVersion && o.hasVersion && v.version != o.version {
+		return v.version < o.version
+	}
+	if !v.versionedAt.IsZero() && !o.versionedAt.IsZero() {
+		return v.versionedAt.Before(o.versionedAt)
+	}
+	return false
+}
+
+func situationVersionOf(sx *siri.PtSituationElement, now time.Time) situationVersion {
+	v := situationVersion{closed: isClosed(sx), seenAt: now}
+	if sx.Version != nil {
+		if n, err := strconv.ParseInt(strings.TrimSpace(*sx.Version), 10, 64); err == nil {
+			v.version, v.hasVersion = n, true
+		}
+	}
+	for _, ts := range []*string{sx.VersionedAtTime, sx.CreationTime} {
+		if ts != nil {
+			if t, ok := siri.ParseISOTime(*ts); ok {
+				v.versionedAt = t
+				break
+			}
+		}
+	}
+	return v
+}
+
+func isClosed(sx *siri.PtSituationElement) bool {
+	return sx.Progress != nil && strings.EqualFold(strings.TrimSpace(*sx.Progress), "closed")
+}
+
+// deletedAlert builds the tombstone emitted for a closed situation. It keeps
+// the informed entities so that the deletion is a well-formed alert.
+func deletedAlert(id string, sx *siri.PtSituationElement, opts Options) *Entity {
+	alert := &gtfs.Alert{}
+	if affects := alertAffects(sx, opts); affects != nil {
+		alert.InformedEntity = mapInformedEntities(affects, situationDate(sx), opts)
+	}
+	ent := &gtfs.FeedEntity{Id: &id, IsDeleted: proto.Bool(true), Alert: alert}
+	return &Entity{ID: id, Datasource: derefString(sx.ParticipantRef), Message: ent, TTL: opts.VMGracePeriod, Deleted: true}
+}
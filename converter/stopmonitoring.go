@@ -0,0 +1,226 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// SM -> TripUpdate / VehiclePosition
+
+// smJourney collects the visits of one journey reported by any monitored stop.
+type smJourney struct {
+	tripID    string
+	mvj       *siri.MonitoredVehicleJourney
+	recorded  time.Time
+	calls     map[string]smCall
+	callOrder []string
+}
+
+type smCall struct {
+	call     *siri.MonitoredCall
+	recorded time.Time
+}
+
+// MapSMToTripUpdates aggregates MonitoredStopVisits by FramedVehicleJourneyRef
+// into one TripUpdate per journey, with a stop_time_update for every stop
+// that reported the journey. Visits without a journey reference are skipped.
+func MapSMToTripUpdates(visits []siri.MonitoredStopVisit, opts Options) []Entity {
+	journeys := make(map[string]*smJourney)
+	var keys []string
+	for i := range visits {
+		v := &visits[i]
+		mvj := v.MonitoredVehicleJourney
+		if mvj == nil || mvj.MonitoredCall == nil || mvj.FramedVehicleJourneyRef == nil || mvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef == nil {
+			continue
+		}
+		tripID := stripPrefix(*mvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef, "SOFIA:ServiceJourney:")
+		key := tripID
+		if mvj.OriginAimedDepartureTime != nil {
+			if t, ok := siri.ParseISOTime(*mvj.OriginAimedDepartureTime); ok {
+				key = tripID + "-" + siri.FormatDateYYYYMMDD(t)
+			}
+		}
+
+		var recorded time.Time
+		if v.RecordedAtTime != nil {
+			recorded, _ = siri.ParseISOTime(*v.RecordedAtTime)
+		}
+		j, ok := journeys[key]
+		if !ok {
+			j = &smJourney{tripID: tripID, mvj: mvj, calls: make(map[string]smCall)}
+			journeys[key] = j
+			keys = append(keys, key)
+		}
+		if !recorded.Before(j.recorded) {
+			j.recorded, j.mvj = recorded, mvj
+		}
+
+		// The same stop may be reported by several visits; keep the newest.
+		callKey := derefString(mvj.MonitoredCall.StopPointRef)
+		if mvj.MonitoredCall.Order != nil {
+			callKey = fmt.Sprintf("%s#%d", callKey, *mvj.MonitoredCall.Order)
+		}
+		prev, seen := j.calls[callKey]
+		if !seen {
+			j.callOrder = append(j.callOrder, callKey)
+		}
+		if !seen || !recorded.Before(prev.recorded) {
+			j.calls[callKey] = smCall{call: mvj.MonitoredCall, recorded: recorded}
+		}
+	}
+
+	out := make([]Entity, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, *smTripUpdate(key, journeys[key], opts))
+	}
+	return out
+}
+
+// withoutEstimated drops stop-visit trip updates for journeys that an ET
+// trip update already covers. A visit without a service day matches every
+// estimated day of its trip.
+func withoutEstimated(ents []Entity, estimated []*gtfs.TripDescriptor) []Entity {
+	if len(estimated) == 0 {
+		return ents
+	}
+	trips := make(map[string]bool, len(estimated))
+	days := make(map[string]bool, len(estimated))
+	for _, td := range estimated {
+		if td == nil {
+			continue
+		}
+		trips[td.GetTripId()] = true
+		days[td.GetTripId()+"|"+td.GetStartDate()] = true
+	}
+	out := ents[:0]
+	for _, e := range ents {
+		td := e.Message.GetTripUpdate().GetTrip()
+		if td.GetStartDate() == "" && trips[td.GetTripId()] || days[td.GetTripId()+"|"+td.GetStartDate()] {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func smTripUpdate(id string, j *smJourney, opts Options) *Entity {
+	calls := make([]*siri.MonitoredCall, 0, len(j.callOrder))
+	for _, k := range j.callOrder {
+		calls = append(calls, j.calls[k].call)
+	}
+	sortMonitoredCalls(calls)
+
+	mvj := j.mvj
+	ent := &gtfs.FeedEntity{Id: &id, IsDeleted: proto.Bool(false)}
+	tu := &gtfs.TripUpdate{}
+	if !j.recorded.IsZero() {
+		tu.Timestamp = proto.Uint64(uint64(j.recorded.Unix()))
+	}
+
+	td := &gtfs.TripDescriptor{
+		TripId:               strPtrOrNil(j.tripID),
+		ScheduleRelationship: gtfs.TripDescriptor_SCHEDULED.Enum(),
+	}
+	if mvj.LineRef != nil {
+		td.RouteId = strPtrOrNil(stripPrefix(*mvj.LineRef, "SOFIA:Line:"))
+	}
+	if mvj.OriginAimedDepartureTime != nil {
+		if t, ok := siri.ParseISOTime(*mvj.OriginAimedDepartureTime); ok {
+			td.StartDate = proto.String(siri.FormatDateYYYYMMDD(t))
+			td.StartTime = proto.String(t.Format("15:04:05"))
+		}
+	}
+	tu.Trip = td
+	if mvj.VehicleRef != nil && *mvj.VehicleRef != "" {
+		tu.Vehicle = &gtfs.VehicleDescriptor{Id: proto.String(stripPrefix(*mvj.VehicleRef, "SOFIA:VehicleRef:"))}
+	}
+
+	var latest time.Time
+	for i, c := range calls {
+		stu := &gtfs.TripUpdate_StopTimeUpdate{ScheduleRelationship: gtfs.TripUpdate_StopTimeUpdate_SCHEDULED.Enum()}
+		if c.StopPointRef != nil {
+			stu.StopId = strPtrOrNil(stripPrefix(*c.StopPointRef, "SOFIA:Quay:"))
+		}
+		stu.StopSequence = stopSequence(c.Order, uint32(i))
+		stu.Arrival = stopTimeEvent(c.ActualArrivalTime, c.ExpectedArrivalTime)
+		stu.Departure = stopTimeEvent(c.ActualDepartureTime, c.ExpectedDepartureTime)
+		for _, ts := range []*string{c.ExpectedArrivalTime, c.AimedArrivalTime, c.ExpectedDepartureTime, c.AimedDepartureTime} {
+			if ts != nil {
+				if t, ok := siri.ParseISOTime(*ts); ok {
+					latest = siri.Latest(latest, t)
+				}
+			}
+		}
+		tu.StopTimeUpdate = append(tu.StopTimeUpdate, stu)
+	}
+	ent.TripUpdate = tu
+
+	ttl := opts.VMGracePeriod
+	if !latest.IsZero() {
+		if d := latest.Sub(opts.now()); d > 0 {
+			ttl = d
+		}
+	}
+	return &Entity{ID: id, Datasource: derefString(mvj.DataSource), Kind: "trip_update", Message: ent, TTL: ttl}
+}
+
+// sortMonitoredCalls orders calls by Order when every call has one, and by
+// aimed time otherwise.
+func sortMonitoredCalls(calls []*siri.MonitoredCall) {
+	byOrder := true
+	for _, c := range calls {
+		if c.Order == nil {
+			byOrder = false
+			break
+		}
+	}
+	sort.SliceStable(calls, func(a, b int) bool {
+		if byOrder {
+			return *calls[a].Order < *calls[b].Order
+		}
+		return aimedTime(calls[a]).Before(aimedTime(calls[b]))
+	})
+}
+
+func aimedTime(c *siri.MonitoredCall) time.Time {
+	for _, ts := range []*string{c.AimedArrivalTime, c.AimedDepartureTime} {
+		if ts != nil {
+			if t, ok := siri.ParseISOTime(*ts); ok {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// stopTimeEvent uses the first parseable time, typically actual then expected.
+func stopTimeEvent(times ...*string) *gtfs.TripUpdate_StopTimeEvent {
+	for _, ts := range times {
+		if ts == nil {
+			continue
+		}
+		if t, ok := siri.ParseISOTime(*ts); ok {
+			return &gtfs.TripUpdate_StopTimeEvent{Time: proto.Int64(t.Unix()), Uncertainty: proto.Int32(0)}
+		}
+	}
+	return nil
+}
+
+// MapSMToVehiclePosition maps the VehicleLocation embedded in a stop visit
+// like a VM VehicleActivity. Visits without a location yield nil.
+func MapSMToVehiclePosition(v *siri.MonitoredStopVisit, opts Options) *Entity {
+	if v == nil || v.MonitoredVehicleJourney == nil || v.MonitoredVehicleJourney.VehicleLocation == nil {
+		return nil
+	}
+	return MapVMToVehiclePosition(&siri.VehicleActivity{
+		RecordedAtTime:          v.RecordedAtTime,
+		ValidUntilTime:          v.ValidUntilTime,
+		MonitoredVehicleJourney: v.MonitoredVehicleJourney,
+	}, opts)
+}
@@ -0,0 +1,91 @@
+package converter
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// StopIndex holds the station hierarchy of a static GTFS feed so that SIRI
+// StopPlace references can be expanded to their child stops. Set
+// Options.Stops to enable resolution.
+type StopIndex struct {
+	parent   map[string]string
+	children map[string][]string
+}
+
+// NewStopIndex returns an empty index.
+func NewStopIndex() *StopIndex {
+	return &StopIndex{
+		parent:   make(map[string]string),
+		children: make(map[string][]string),
+	}
+}
+
+// LoadStopIndex reads a GTFS stops.txt and indexes its parent_station column.
+func LoadStopIndex(r io.Reader) (*StopIndex, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	stopCol, parentCol := -1, -1
+	for i, h := range header {
+		switch strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")) {
+		case "stop_id":
+			stopCol = i
+		case "parent_station":
+			parentCol = i
+		}
+	}
+	if stopCol < 0 {
+		return nil, errors.New("stops.txt: missing stop_id column")
+	}
+
+	idx := NewStopIndex()
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if stopCol >= len(rec) {
+			continue
+		}
+		var parent string
+		if parentCol >= 0 && parentCol < len(rec) {
+			parent = rec[parentCol]
+		}
+		idx.Add(rec[stopCol], parent)
+	}
+	return idx, nil
+}
+
+// Add registers a stop and its parent station (empty for top-level stops).
+func (x *StopIndex) Add(stopID, parentStation string) {
+	if stopID == "" || parentStation == "" {
+		return
+	}
+	x.parent[stopID] = parentStation
+	x.children[parentStation] = append(x.children[parentStation], stopID)
+}
+
+// Parent returns the parent station of stopID, or "" if it has none.
+func (x *StopIndex) Parent(stopID string) string {
+	if x == nil {
+		return ""
+	}
+	return x.parent[stopID]
+}
+
+// Children returns the stops whose parent_station is stationID.
+func (x *StopIndex) Children(stationID string) []string {
+	if x == nil {
+		return nil
+	}
+	return x.children[stationID]
+}
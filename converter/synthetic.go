@@ -0,0 +1,249 @@
+package converter
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// SyntheticAlerts configures alerts derived from ET and VM data for journeys
+// that are cancelled or heavily delayed but not covered by any SX situation.
+// Set Options.SyntheticAlerts to enable it.
+type SyntheticAlerts struct {
+	Cancellations  bool          // NO_SERVICE alerts for cancelled ET journeys
+	DelayThreshold time.Duration // SIGNIFICANT_DELAYS alerts at or above this delay (zero disables)
+
+	// Templates holds the texts per language code. Placeholders: {route},
+	// {trip}, {start_time} (HH:MM) and {delay} (whole minutes).
+	Templates map[string]AlertTemplate
+}
+
+// AlertTemplate is the text of synthetic alerts in one language.
+type AlertTemplate struct {
+	CancelledHeader      string
+	CancelledDescription string
+	DelayedHeader        string
+	DelayedDescription   string
+}
+
+// DefaultSyntheticAlerts enables cancellation alerts and delay alerts from
+// 15 minutes with Bulgarian and English texts.
+func DefaultSyntheticAlerts() *SyntheticAlerts {
+	return &SyntheticAlerts{
+		Cancellations:  true,
+		DelayThreshold: 15 * time.Minute,
+		Templates: map[string]AlertTemplate{
+			"bg": {
+				CancelledHeader:      "Линия {route}: курсът в {start_time} е отменен",
+				CancelledDescription: "Курсът по линия {route} с тръгване в {start_time} няма да се изпълни.",
+				DelayedHeader:        "Линия {route}: закъснение {delay} мин.",
+				DelayedDescription:   "Курсът по линия {route} с тръгване в {start_time} закъснява с около {delay} минути.",
+			},
+			"en": {
+				CancelledHeader:      "Line {route}: {start_time} trip cancelled",
+				CancelledDescription: "The {start_time} trip on line {route} will not run.",
+				DelayedHeader:        "Line {route}: {delay} min delay",
+				DelayedDescription:   "The {start_time} trip on line {route} is running about {delay} minutes late.",
+			},
+		},
+	}
+}
+
+// MapETToAlert derives a NO_SERVICE or SIGNIFICANT_DELAYS alert from the trip
+// update of an estimated vehicle journey. It returns nil when synthetic
+// alerts are disabled or the journey runs within the threshold.
+func MapETToAlert(evj *siri.EstimatedVehicleJourney, opts Options) *Entity {
+	if opts.SyntheticAlerts == nil {
+		return nil
+	}
+	return tripUpdateAlert(evj, MapETToTripUpdate(evj, opts), opts)
+}
+
+// tripUpdateAlert builds the alert for a trip update; journeys known to the
+// timetable also inform their operator and vehicle mode.
+func tripUpdateAlert(evj *siri.EstimatedVehicleJourney, tu *Entity, opts Options) *Entity {
+	if tu == nil || tu.Message.GetTripUpdate().GetTrip() == nil {
+		return nil
+	}
+	a := journeyAlert(evj, tu, opts.SyntheticAlerts)
+	if a == nil {
+		return nil
+	}
+	trip := tu.Message.TripUpdate.Trip
+	if pj := opts.Timetable.lookup(trip.GetTripId(), trip.GetStartDate()); pj != nil {
+		pj.describe(a.Message.Alert.InformedEntity[0])
+	}
+	return a
+}
+
+func journeyAlert(evj *siri.EstimatedVehicleJourney, tu *Entity, cfg *SyntheticAlerts) *Entity {
+	trip := tu.Message.TripUpdate.Trip
+	start := startTimeOf(evj.OriginAimedDepartureTime)
+
+	if evj.Cancellation != nil && *evj.Cancellation {
+		if !cfg.Cancellations {
+			return nil
+		}
+		header, description := cfg.texts(true, trip, start, 0)
+		return syntheticAlert("cancelled-"+tu.ID, tu.Datasource, tu.TTL, trip, gtfs.Alert_NO_SERVICE, header, description)
+	}
+
+	delay, ok := journeyDelay(evj)
+	if !ok || cfg.DelayThreshold <= 0 || delay < cfg.DelayThreshold {
+		return nil
+	}
+	header, description := cfg.texts(false, trip, start, delay)
+	return syntheticAlert("delayed-"+tu.ID, tu.Datasource, tu.TTL, trip, gtfs.Alert_SIGNIFICANT_DELAYS, header, description)
+}
+
+// MapVMToAlert derives a SIGNIFICANT_DELAYS alert from the Delay reported for
+// a monitored vehicle journey.
+func MapVMToAlert(va *siri.VehicleActivity, opts Options) *Entity {
+	cfg := opts.SyntheticAlerts
+	if cfg == nil || cfg.DelayThreshold <= 0 || va == nil || va.MonitoredVehicleJourney == nil {
+		return nil
+	}
+	mvj := va.MonitoredVehicleJourney
+	if mvj.Delay == nil || mvj.FramedVehicleJourneyRef == nil || mvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef == nil {
+		return nil
+	}
+	delay, ok := siri.ParseDuration(strings.TrimSpace(*mvj.Delay))
+	if !ok || delay < cfg.DelayThreshold {
+		return nil
+	}
+
+	trip := &gtfs.TripDescriptor{TripId: strPtrOrNil(stripPrefix(*mvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef, "SOFIA:ServiceJourney:"))}
+	if mvj.LineRef != nil {
+		trip.RouteId = strPtrOrNil(stripPrefix(*mvj.LineRef, "SOFIA:Line:"))
+	}
+	if mvj.OriginAimedDepartureTime != nil {
+		if t, ok := siri.ParseISOTime(*mvj.OriginAimedDepartureTime); ok {
+			trip.StartDate = proto.String(siri.FormatDateYYYYMMDD(t))
+		}
+	}
+	id := trip.GetTripId()
+	if trip.GetStartDate() != "" {
+		id += "-" + trip.GetStartDate()
+	}
+	header, description := cfg.texts(false, trip, startTimeOf(mvj.OriginAimedDepartureTime), delay)
+	return syntheticAlert("delayed-"+id, derefString(mvj.DataSource), opts.VMGracePeriod, trip, gtfs.Alert_SIGNIFICANT_DELAYS, header, description)
+}
+
+// journeyDelay returns the delay at the next estimated call, falling back to
+// the last recorded call.
+func journeyDelay(evj *siri.EstimatedVehicleJourney) (time.Duration, bool) {
+	for _, ec := range evj.EstimatedCalls {
+		if d, ok := callDelay(ec.AimedArrivalTime, nil, ec.ExpectedArrivalTime); ok {
+			return d, true
+		}
+		if d, ok := callDelay(ec.AimedDepartureTime, nil, ec.ExpectedDepartureTime); ok {
+			return d, true
+		}
+	}
+	for i := len(evj.RecordedCalls) - 1; i >= 0; i-- {
+		rc := evj.RecordedCalls[i]
+		if d, ok := callDelay(rc.AimedDepartureTime, rc.ActualDepartureTime, rc.ExpectedDepartureTime); ok {
+			return d, true
+		}
+		if d, ok := callDelay(rc.AimedArrivalTime, rc.ActualArrivalTime, rc.ExpectedArrivalTime); ok {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func callDelay(aimed, actual, expected *string) (time.Duration, bool) {
+	if aimed == nil {
+		return 0, false
+	}
+	d := delaySeconds(aimed, actual, expected)
+	if d == nil {
+		return 0, false
+	}
+	return time.Duration(*d) * time.Second, true
+}
+
+func startTimeOf(originAimed *string) string {
+	if originAimed != nil {
+		if t, ok := siri.ParseISOTime(*originAimed); ok {
+			return t.Format("15:04")
+		}
+	}
+	return ""
+}
+
+// texts renders the header and description templates, languages sorted.
+func (c *SyntheticAlerts) texts(cancelled bool, trip *gtfs.TripDescriptor, start string, delay time.Duration) (header, description *gtfs.TranslatedString) {
+	langs := make([]string, 0, len(c.Templates))
+	for lang := range c.Templates {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	r := strings.NewReplacer(
+		"{route}", trip.GetRouteId(),
+		"{trip}", trip.GetTripId(),
+		"{start_time}", start,
+		"{delay}", strconv.Itoa(int(delay.Round(time.Minute)/time.Minute)),
+	)
+	header, description = &gtfs.TranslatedString{}, &gtfs.TranslatedString{}
+	for _, lang := range langs {
+		tpl := c.Templates[lang]
+		h, d := tpl.DelayedHeader, tpl.DelayedDescription
+		if cancelled {
+			h, d = tpl.CancelledHeader, tpl.CancelledDescription
+		}
+		header.Translation = append(header.Translation, &gtfs.TranslatedString_Translation{Text: proto.String(r.Replace(h)), Language: strPtr(lang)})
+		description.Translation = append(description.Translation, &gtfs.TranslatedString_Translation{Text: proto.String(r.Replace(d)), Language: strPtr(lang)})
+	}
+	return header, description
+}
+
+func syntheticAlert(id, datasource string, ttl time.Duration, trip *gtfs.TripDescriptor, effect gtfs.Alert_Effect, header, description *gtfs.TranslatedString) *Entity {
+	trip = proto.Clone(trip).(*gtfs.TripDescriptor)
+	trip.ScheduleRelationship = nil
+	sel := &gtfs.EntitySelector{Trip: trip, RouteId: strPtrOrNil(trip.GetRouteId())}
+	alert := &gtfs.Alert{
+		Cause:           gtfs.Alert_UNKNOWN_CAUSE.Enum(),
+		Effect:          effect.Enum(),
+		HeaderText:      header,
+		DescriptionText: description,
+		InformedEntity:  []*gtfs.EntitySelector{sel},
+	}
+	ent := &gtfs.FeedEntity{Id: &id, IsDeleted: proto.Bool(false), Alert: alert}
+	return &Entity{ID: id, Datasource: datasource, Kind: "alert", Message: ent, TTL: ttl}
+}
+
+// withoutSituations drops synthetic alerts for trips, or whole routes,
+// already informed by an SX alert.
+func withoutSituations(synthetic, situations []Entity) []Entity {
+	trips := make(map[string]bool)
+	routes := make(map[string]bool)
+	for _, a := range situations {
+		if a.Deleted || a.Message.GetAlert() == nil {
+			continue
+		}
+		for _, sel := range a.Message.Alert.InformedEntity {
+			switch {
+			case sel.GetTrip().GetTripId() != "":
+				trips[sel.Trip.GetTripId()] = true
+			case sel.GetRouteId() != "" && sel.StopId == nil:
+				routes[sel.GetRouteId()] = true
+			}
+		}
+	}
+	out := synthetic[:0]
+	for _, s := range synthetic {
+		trip := s.Message.Alert.InformedEntity[0].Trip
+		if !trips[trip.GetTripId()] && !routes[trip.GetRouteId()] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,274 @@
+package converter
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// Timetable holds the dated journeys of SIRI-PT deliveries so that ET trip
+// updates can be completed with route, direction and headsign, and planned
+// extra journeys can be published as ADDED trips. Set Options.Timetable to
+// share it across conversions. It is safe for concurrent use.
+type Timetable struct {
+	// Retention is how long a journey is kept after its last planned call,
+	// so that late running trips can still be completed; ConvertSIRI prunes
+	// older ones. Zero keeps them until Prune.
+	Retention time.Duration
+
+	mu       sync.Mutex
+	journeys map[string]*plannedJourney // by trip ID and start date
+	byTrip   map[string]string          // trip ID -> key of the last loaded journey
+}
+
+type plannedJourney struct {
+	key         string
+	tripID      string
+	startDate   string
+	startTime   string
+	routeID     string
+	directionID *uint32
+	headsign    string
+	agencyID    string
+	routeType   *int32
+	datasource  string
+	extra       bool
+	cancelled   bool
+	calls       []siri.DatedCall
+	end         time.Time
+}
+
+// DefaultTimetableRetention is how long NewTimetable keeps finished journeys.
+const DefaultTimetableRetention = 6 * time.Hour
+
+// NewTimetable returns an empty timetable with DefaultTimetableRetention.
+func NewTimetable() *Timetable {
+	return &Timetable{
+		Retention: DefaultTimetableRetention,
+		journeys:  make(map[string]*plannedJourney),
+		byTrip:    make(map[string]string),
+	}
+}
+
+// Len returns the number of journeys currently held.
+func (tt *Timetable) Len() int {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	return len(tt.journeys)
+}
+
+// Prune forgets journeys whose last planned call is before the given time.
+func (tt *Timetable) Prune(before time.Time) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	for key, pj := range tt.journeys {
+		if !pj.end.IsZero() && pj.end.Before(before) {
+			delete(tt.journeys, key)
+			if tt.byTrip[pj.tripID] == key {
+				delete(tt.byTrip, pj.tripID)
+			}
+		}
+	}
+}
+
+// Load adds or replaces the dated journeys of a PT delivery.
+func (tt *Timetable) Load(d *siri.ProductionTimetableDelivery) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	if tt.journeys == nil {
+		tt.journeys = make(map[string]*plannedJourney)
+		tt.byTrip = make(map[string]string)
+	}
+	for _, f := range d.DatedTimetableVersionFrames {
+		for _, dvj := range f.DatedVehicleJourneys {
+			if pj := newPlannedJourney(f, dvj); pj != nil {
+				tt.journeys[pj.key] = pj
+				tt.byTrip[pj.tripID] = pj.key
+			}
+		}
+	}
+}
+
+// lookup finds the journey for a trip, on startDate when it is known.
+func (tt *Timetable) lookup(tripID, startDate string) *plannedJourney {
+	if tt == nil {
+		return nil
+	}
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	if startDate != "" {
+		return tt.journeys[tripID+"-"+startDate]
+	}
+	return tt.journeys[tt.byTrip[tripID]]
+}
+
+// extraJourneys returns the planned extra journeys not yet finished at now,
+// ordered by key.
+func (tt *Timetable) extraJourneys(now time.Time) []*plannedJourney {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	var out []*plannedJourney
+	for _, pj := range tt.journeys {
+		if pj.extra && !pj.cancelled && (pj.end.IsZero() || pj.end.After(now)) {
+			out = append(out, pj)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].key < out[j].key })
+	return out
+}
+
+func newPlannedJourney(f siri.DatedTimetableVersionFrame, dvj siri.DatedVehicleJourney) *plannedJourney {
+	ref := dvj.DatedVehicleJourneyCode
+	dataFrame := dvj.DataFrameRef
+	if dvj.FramedVehicleJourneyRef != nil {
+		if ref == nil {
+			ref = dvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef
+		}
+		if dataFrame == nil {
+			dataFrame = dvj.FramedVehicleJourneyRef.DataFrameRef
+		}
+	}
+	if ref == nil || *ref == "" {
+		return nil
+	}
+
+	pj := &plannedJourney{
+		tripID:     stripPrefix(*ref, "SOFIA:ServiceJourney:"),
+		datasource: derefString(dvj.DataSource),
+		extra:      dvj.ExtraJourney != nil && *dvj.ExtraJourney,
+		cancelled:  dvj.Cancellation != nil && *dvj.Cancellation,
+		calls:      append([]siri.DatedCall(nil), dvj.DatedCalls...),
+	}
+	if line := firstString(dvj.LineRef, f.LineRef); line != "" {
+		pj.routeID = stripPrefix(line, "SOFIA:Line:")
+	}
+	if dir, ok := directionID(firstString(dvj.DirectionRef, f.DirectionRef)); ok {
+		pj.directionID = &dir
+	}
+	pj.headsign = strings.TrimSpace(derefString(dvj.DestinationDisplay))
+	if op := firstString(dvj.OperatorRef); op != "" {
+		pj.agencyID = stripPrefix(op, "SOFIA:Operator:")
+	}
+	if rt, ok := routeTypeFromVehicleMode(derefString(dvj.VehicleMode)); ok {
+		pj.routeType = &rt
+	}
+
+	sort.SliceStable(pj.calls, func(i, j int) bool {
+		return derefInt32(pj.calls[i].Order) < derefInt32(pj.calls[j].Order)
+	})
+	for i, c := range pj.calls {
+		for _, ts := range []*string{c.AimedDepartureTime, c.AimedArrivalTime} {
+			if ts == nil {
+				continue
+			}
+			t, ok := siri.ParseISOTime(*ts)
+			if !ok {
+				continue
+			}
+			if pj.startTime == "" {
+				// ET keys journeys by the date of the origin departure.
+				pj.startDate = siri.FormatDateYYYYMMDD(t)
+				pj.startTime = t.Format("15:04:05")
+			}
+			pj.end = siri.Latest(pj.end, t)
+		}
+		if pj.headsign == "" && i == 0 {
+			pj.headsign = strings.TrimSpace(derefString(c.DestinationDisplay))
+		}
+	}
+	if pj.startDate == "" {
+		pj.startDate, _ = sanitizeDate(derefString(dataFrame))
+	}
+
+	pj.key = pj.tripID
+	if pj.startDate != "" {
+		pj.key = pj.tripID + "-" + pj.startDate
+	}
+	return pj
+}
+
+// apply completes an ET trip update with the planned journey's route,
+// direction and headsign, and marks extra journeys as ADDED.
+func (pj *plannedJourney) apply(tu *gtfs.TripUpdate) {
+	td := tu.Trip
+	if td.GetRouteId() == "" {
+		td.RouteId = strPtrOrNil(pj.routeID)
+	}
+	if td.DirectionId == nil && pj.directionID != nil {
+		td.DirectionId = proto.Uint32(*pj.directionID)
+	}
+	if td.GetStartDate() == "" {
+		td.StartDate = strPtrOrNil(pj.startDate)
+	}
+	if td.GetStartTime() == "" {
+		td.StartTime = strPtrOrNil(pj.startTime)
+	}
+	if pj.extra && td.GetScheduleRelationship() == gtfs.TripDescriptor_SCHEDULED {
+		td.ScheduleRelationship = gtfs.TripDescriptor_ADDED.Enum()
+	}
+	if pj.headsign != "" && tu.TripProperties == nil {
+		tu.TripProperties = &gtfs.TripUpdate_TripProperties{}
+		gtfsrt.SetTripHeadsign(tu.TripProperties, pj.headsign)
+	}
+}
+
+// describe adds the operator and vehicle mode of the planned journey to a
+// selector as agency_id and route_type. GTFS-RT trip descriptors have no
+// such fields, so they only reach riders through alert informed entities.
+func (pj *plannedJourney) describe(sel *gtfs.EntitySelector) {
+	if sel.AgencyId == nil {
+		sel.AgencyId = strPtrOrNil(pj.agencyID)
+	}
+	if sel.RouteType == nil && pj.routeType != nil {
+		sel.RouteType = proto.Int32(*pj.routeType)
+	}
+}
+
+// plannedTripUpdate publishes an extra journey from its planned times.
+func plannedTripUpdate(pj *plannedJourney, opts Options) *Entity {
+	id := pj.key
+	ent := &gtfs.FeedEntity{Id: &id, IsDeleted: proto.Bool(false)}
+	tu := &gtfs.TripUpdate{Trip: &gtfs.TripDescriptor{TripId: strPtrOrNil(pj.tripID)}}
+	pj.apply(tu)
+
+	for i, c := range pj.calls {
+		stu := &gtfs.TripUpdate_StopTimeUpdate{ScheduleRelationship: gtfs.TripUpdate_StopTimeUpdate_SCHEDULED.Enum()}
+		if c.StopPointRef != nil {
+			stu.StopId = strPtrOrNil(stripPrefix(*c.StopPointRef, "SOFIA:Quay:"))
+		}
+		stu.StopSequence = stopSequence(c.Order, uint32(i))
+		stu.Arrival = stopTimeEvent(c.AimedArrivalTime)
+		stu.Departure = stopTimeEvent(c.AimedDepartureTime)
+		tu.StopTimeUpdate = append(tu.StopTimeUpdate, stu)
+	}
+	ent.TripUpdate = tu
+
+	ttl := opts.VMGracePeriod
+	if d := pj.end.Sub(opts.now()); !pj.end.IsZero() && d > 0 {
+		ttl = d
+	}
+	return &Entity{ID: id, Datasource: pj.datasource, Kind: "trip_update", Message: ent, TTL: ttl}
+}
+
+func firstString(values ...*string) string {
+	for _, v := range values {
+		if v != nil && strings.TrimSpace(*v) != "" {
+			return strings.TrimSpace(*v)
+		}
+	}
+	return ""
+}
+
+func derefInt32(p *int32) int32 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
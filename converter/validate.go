@@ -0,0 +1,144 @@
+package converter
+
+import (
+	"math"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// Rejection reasons reported through Options.OnReject.
+const (
+	RejectMissingPosition    = "missing_position"
+	RejectInvalidCoordinates = "invalid_coordinates"
+	RejectNullIsland         = "null_island"
+	RejectOutsideBoundingBox = "outside_bounding_box"
+	RejectStale              = "stale"
+	RejectFutureTimestamp    = "future_timestamp"
+	RejectExpired            = "expired"
+	RejectOutdatedVersion    = "outdated_version"
+	RejectClosed             = "closed"
+	RejectNotYetPublished    = "not_yet_published"
+	RejectPublicationEnded   = "publication_ended"
+	RejectNoInformedEntity   = "no_informed_entity"
+)
+
+// Rejection describes a SIRI record that was dropped during conversion.
+type Rejection struct {
+	Kind   string // "trip_update" | "vehicle_position" | "alert"
+	ID     string
+	Reason string
+}
+
+// BoundingBox is a WGS84 area used to discard positions outside the service area.
+type BoundingBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// Contains reports whether the point lies inside the box (edges inclusive).
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// SpeedUnit is the unit producers use for MonitoredVehicleJourney/Velocity.
+type SpeedUnit int
+
+const (
+	// SpeedMetersPerSecond passes Velocity through unchanged (GTFS-RT uses m/s).
+	SpeedMetersPerSecond SpeedUnit = iota
+	// SpeedKilometersPerHour converts Velocity from km/h to m/s.
+	SpeedKilometersPerHour
+)
+
+func (o Options) reject(kind, id, reason string) {
+	if o.OnReject != nil {
+		o.OnReject(Rejection{Kind: kind, ID: id, Reason: reason})
+	}
+}
+
+// validatePosition checks the vehicle location against the configured rules and
+// returns the GTFS-RT position, or nil and the rejection reason. A journey
+// without VehicleLocation is rejected as RejectMissingPosition.
+func validatePosition(mvj *siri.MonitoredVehicleJourney, opts Options) (*gtfs.Position, string) {
+	if mvj.VehicleLocation == nil {
+		return nil, RejectMissingPosition
+	}
+	lat, lon := mvj.VehicleLocation.Latitude, mvj.VehicleLocation.Longitude
+	if math.IsNaN(lat) || math.IsNaN(lon) || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return nil, RejectInvalidCoordinates
+	}
+	if opts.DropNullIsland && lat == 0 && lon == 0 {
+		return nil, RejectNullIsland
+	}
+	if opts.BoundingBox != nil && !opts.BoundingBox.Contains(lat, lon) {
+		return nil, RejectOutsideBoundingBox
+	}
+
+	pos := &gtfs.Position{Latitude: proto.Float32(float32(lat)), Longitude: proto.Float32(float32(lon))}
+	if mvj.Bearing != nil {
+		pos.Bearing = sanitizeBearing(*mvj.Bearing, opts.NormalizeBearing)
+	}
+	if mvj.Velocity != nil {
+		pos.Speed = sanitizeSpeed(*mvj.Velocity, opts.VelocityUnit)
+	}
+	return pos, ""
+}
+
+// checkFreshness applies the staleness policy to a vehicle activity and
+// returns the rejection reason, or "" when the activity is current.
+func checkFreshness(va *siri.VehicleActivity, now time.Time, opts Options) string {
+	if va.RecordedAtTime != nil {
+		if t, ok := siri.ParseISOTime(*va.RecordedAtTime); ok {
+			if opts.VMMaxAge > 0 && now.Sub(t) > opts.VMMaxAge {
+				return RejectStale
+			}
+			if opts.VMFutureTolerance > 0 && t.Sub(now) > opts.VMFutureTolerance {
+				return RejectFutureTimestamp
+			}
+		}
+	}
+	if opts.DropExpiredVehicles && va.ValidUntilTime != nil {
+		if t, ok := siri.ParseISOTime(*va.ValidUntilTime); ok && !t.After(now) {
+			return RejectExpired
+		}
+	}
+	return ""
+}
+
+// sanitizeBearing drops non-finite bearings and, when normalize is set, wraps
+// the value into [0, 360). Out-of-range bearings are dropped otherwise.
+func sanitizeBearing(b float32, normalize bool) *float32 {
+	v := float64(b)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	if normalize {
+		v = math.Mod(v, 360)
+		if v < 0 {
+			v += 360
+		}
+	} else if v < 0 || v >= 360 {
+		return nil
+	}
+	out := float32(v)
+	return &out
+}
+
+// sanitizeSpeed converts the producer speed to m/s and drops negative or
+// non-finite values.
+func sanitizeSpeed(v float64, unit SpeedUnit) *float32 {
+	if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+		return nil
+	}
+	if unit == SpeedKilometersPerHour {
+		v = v / 3.6
+	}
+	out := float32(v)
+	return &out
+}
@@ -4,9 +4,10 @@
 // in various formats. Currently supports:
 //   - XML: SIRI XML message decoding
 //   - Base64-encoded XML: Streaming decoder for optimal performance
+//   - JSON: SIRI JSON / SIRI Lite decoding into the same ServiceDelivery
+//   - Streaming XML: per-element callbacks for very large deliveries
 //
 // Future support may include:
-//   - JSON: SIRI JSON encoding/decoding
 //   - Custom formatters for specific data sources
 //
 // # Basic XML Decoding
@@ -28,4 +29,26 @@
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
+//
+// # Streaming Large Deliveries
+//
+// StreamSIRI hands each EstimatedVehicleJourney, VehicleActivity and
+// PtSituationElement to a callback as soon as it is decoded, keeping memory
+// constant regardless of document size:
+//
+//	err := formatter.StreamSIRI(file, formatter.Handler{
+//	    VehicleActivity: func(va *siri.VehicleActivity) error {
+//	        return publish(converter.MapVMToVehiclePosition(va, opts))
+//	    },
+//	})
+//
+// # SIRI JSON Decoding
+//
+// Capitalized (Entur) and lowerCamel keys are both accepted, and repeated
+// elements may be a single object or an array:
+//
+//	serviceDelivery, err := formatter.DecodeSIRIJSON(resp.Body)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
 package formatter
@@ -0,0 +1,226 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// DecodeSIRIJSON reads SIRI JSON (SIRI Lite) and returns a populated
+// ServiceDelivery. The document may be wrapped in "Siri" or start at
+// "ServiceDelivery". Keys are matched case-insensitively against the SIRI
+// element names, so both Entur-style capitalized keys and lowerCamel keys
+// work, and repeated elements may be sent as an array or a single object.
+// Translated texts may be plain strings or {"value": ..., "lang": ...}.
+func DecodeSIRIJSON(r io.Reader) (*siri.ServiceDelivery, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode SIRI JSON: %w", err)
+	}
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("decode SIRI JSON: expected an object, got %T", doc)
+	}
+	if v, ok := lookupKey(root, "Siri"); ok {
+		if m, ok := v.(map[string]interface{}); ok {
+			root = m
+		}
+	}
+	if v, ok := lookupKey(root, "ServiceDelivery"); ok {
+		if m, ok := v.(map[string]interface{}); ok {
+			root = m
+		}
+	}
+
+	var sd siri.ServiceDelivery
+	fillValue(reflect.ValueOf(&sd).Elem(), root)
+	return &sd, nil
+}
+
+// resolver is implemented by types that post-process decoded values, such
+// as siri.Location projecting coordinates to WGS84.
+type resolver interface {
+	Resolve() bool
+}
+
+// fillValue stores the JSON value v into dst, driven by dst's type and the
+// xml tags of its fields. Values that do not fit are ignored.
+func fillValue(dst reflect.Value, v interface{}) {
+	if v == nil {
+		return
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if arr, ok := v.([]interface{}); ok {
+			// A single element sent as an array: take the first item.
+			if len(arr) == 0 {
+				return
+			}
+			v = arr[0]
+		}
+		elem := reflect.New(dst.Type().Elem())
+		fillValue(elem.Elem(), v)
+		dst.Set(elem)
+	case reflect.Slice:
+		arr, ok := v.([]interface{})
+		if !ok {
+			arr = []interface{}{v}
+		}
+		out := reflect.MakeSlice(dst.Type(), 0, len(arr))
+		for _, item := range arr {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			fillValue(elem, item)
+			out = reflect.Append(out, elem)
+		}
+		dst.Set(out)
+	case reflect.Struct:
+		fillStruct(dst, v)
+	default:
+		fillScalar(dst, v)
+	}
+}
+
+func fillStruct(dst reflect.Value, v interface{}) {
+	if arr, ok := v.([]interface{}); ok {
+		if len(arr) == 0 {
+			return
+		}
+		v = arr[0]
+	}
+	obj, isObj := v.(map[string]interface{})
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, chardata, attr := xmlFieldName(f.Tag.Get("xml"))
+		switch {
+		case chardata:
+			if !isObj {
+				fillScalar(dst.Field(i), v)
+			} else if val, ok := lookupKey(obj, "value", "content", "text"); ok {
+				fillScalar(dst.Field(i), val)
+			}
+		case !isObj || name == "" || name == "-":
+		case attr && name == "lang":
+			if val, ok := lookupKey(obj, "lang", "xml:lang"); ok {
+				fillScalar(dst.Field(i), val)
+			}
+		default:
+			if val, ok := lookupPath(obj, strings.Split(name, ">")); ok {
+				fillValue(dst.Field(i), val)
+			}
+		}
+	}
+	if r, ok := dst.Addr().Interface().(resolver); ok {
+		r.Resolve()
+	}
+}
+
+// lookupPath follows an xml "A>B" path. When a wrapper element holds the
+// list directly (e.g. "RecordedCalls": [...]) the list is used as is.
+func lookupPath(obj map[string]interface{}, path []string) (interface{}, bool) {
+	v, ok := lookupKey(obj, path[0])
+	if !ok || len(path) == 1 {
+		return v, ok
+	}
+	if inner, isObj := v.(map[string]interface{}); isObj {
+		return lookupPath(inner, path[1:])
+	}
+	if _, isArr := v.([]interface{}); isArr && len(path) == 2 {
+		return v, true
+	}
+	return nil, false
+}
+
+func lookupKey(obj map[string]interface{}, names ...string) (interface{}, bool) {
+	for _, name := range names {
+		if v, ok := obj[name]; ok {
+			return v, true
+		}
+	}
+	for k, v := range obj {
+		for _, name := range names {
+			if strings.EqualFold(k, name) {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// xmlFieldName returns the local element name of an xml struct tag and
+// whether the field is chardata or an attribute.
+func xmlFieldName(tag string) (name string, chardata, attr bool) {
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		name = name[i+1:] // drop the namespace URI
+	}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "chardata":
+			chardata = true
+		case "attr":
+			attr = true
+		}
+	}
+	return name, chardata, attr
+}
+
+func fillScalar(dst reflect.Value, v interface{}) {
+	s := scalarString(v)
+	if s == "" && dst.Kind() != reflect.String {
+		return
+	}
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(s)
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			dst.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil && !dst.OverflowInt(n) {
+			dst.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(s, 10, 64); err == nil && !dst.OverflowUint(n) {
+			dst.SetUint(n)
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			dst.SetFloat(f)
+		}
+	}
+}
+
+// scalarString renders a JSON scalar as the text an XML element would hold.
+// Objects yield their "value" member and arrays their first item.
+func scalarString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	case map[string]interface{}:
+		if val, ok := lookupKey(x, "value", "content", "text"); ok {
+			return scalarString(val)
+		}
+	case []interface{}:
+		if len(x) > 0 {
+			return scalarString(x[0])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package formatter
+
+import (
+	stdxml "encoding/xml"
+	"io"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// Handler receives SIRI elements as StreamSIRI decodes them. Elements whose
+// callback is nil are skipped without being decoded. Returning an error from
+// a callback stops the stream and StreamSIRI returns that error.
+type Handler struct {
+	EstimatedVehicleJourney func(*siri.EstimatedVehicleJourney) error
+	VehicleActivity         func(*siri.VehicleActivity) error
+	PtSituationElement      func(*siri.PtSituationElement) error
+}
+
+// StreamSIRI walks SIRI XML token by token and invokes the handler for every
+// EstimatedVehicleJourney, VehicleActivity and PtSituationElement, so only
+// one element is held in memory at a time. Elements may appear anywhere in
+// the document; the surrounding Siri/ServiceDelivery envelope is not needed.
+//
+// Example usage:
+//
+//	err := formatter.StreamSIRI(file, formatter.Handler{
+//	    EstimatedVehicleJourney: func(evj *siri.EstimatedVehicleJourney) error {
+//	        if e := converter.MapETToTripUpdate(evj, opts); e != nil {
+//	            entities = append(entities, *e)
+//	        }
+//	        return nil
+//	    },
+//	})
+func StreamSIRI(r io.Reader, h Handler) error {
+	dec := stdxml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(stdxml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "EstimatedVehicleJourney":
+			err = streamElement(dec, &start, h.EstimatedVehicleJourney)
+		case "VehicleActivity":
+			err = streamElement(dec, &start, h.VehicleActivity)
+		case "PtSituationElement":
+			err = streamElement(dec, &start, h.PtSituationElement)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// streamElement decodes the element starting at start and passes it to fn,
+// or skips it when fn is nil.
+func streamElement[T any](dec *stdxml.Decoder, start *stdxml.StartElement, fn func(*T) error) error {
+	if fn == nil {
+		return dec.Skip()
+	}
+	var v T
+	if err := dec.DecodeElement(&v, start); err != nil {
+		return err
+	}
+	return fn(&v)
+}
@@ -2,13 +2,15 @@
 //
 // GTFS-Realtime is a standard for providing real-time transit updates.
 // This package provides:
-//   - Internal type definitions that mirror the protobuf schema
-//   - Conversion functions to official MobilityData protobuf types
+//   - Internal type definitions that mirror the protobuf schema, used as the
+//     JSON view of MobilityData protobuf messages (FromProto, ToProto)
+//   - Accessors for fields newer than the bundled bindings, which travel as
+//     unknown protobuf fields
 //   - Serialization to Protocol Buffer format
 //   - Utilities for unmarshaling and JSON conversion
 //
-// The package uses internal types during conversion before final serialization
-// to allow for flexible manipulation before protobuf encoding.
+// The converter builds MobilityData protobuf messages directly; the internal
+// types are derived from them for encoding/json output.
 //
 // Example:
 //
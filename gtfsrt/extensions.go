@@ -0,0 +1,254 @@
+package gtfsrt
+
+import (
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// GTFS-RT fields newer than the bundled bindings. They travel as unknown
+// fields of the binding messages, so consumers with current bindings decode
+// them normally; the accessors below set and read them.
+const (
+	alertFieldImage                 = 15
+	alertFieldImageAlternativeText  = 16
+	alertFieldCauseDetail           = 17
+	alertFieldEffectDetail          = 18
+	tripPropertiesFieldTripHeadsign = 5
+)
+
+// SetAlertImage sets Alert.image; nil clears it. The bindings have no
+// TranslatedImage message, so the internal type is used.
+func SetAlertImage(pa *gtfs.Alert, img *TranslatedImage) {
+	if img == nil {
+		setUnknown(pa, alertFieldImage, nil, false)
+		return
+	}
+	setUnknown(pa, alertFieldImage, marshalTranslatedImage(img), true)
+}
+
+// AlertImage returns Alert.image, or nil when it is not set.
+func AlertImage(pa *gtfs.Alert) *TranslatedImage {
+	values := unknownFields(pa, alertFieldImage)
+	if len(values) == 0 {
+		return nil
+	}
+	img := &TranslatedImage{}
+	for _, v := range values {
+		img.LocalizedImage = append(img.LocalizedImage, unmarshalLocalizedImages(v)...)
+	}
+	return img
+}
+
+// SetAlertImageAlternativeText sets Alert.image_alternative_text; nil clears it.
+func SetAlertImageAlternativeText(pa *gtfs.Alert, ts *gtfs.TranslatedString) {
+	setTranslatedString(pa, alertFieldImageAlternativeText, ts)
+}
+
+// AlertImageAlternativeText returns Alert.image_alternative_text, or nil.
+func AlertImageAlternativeText(pa *gtfs.Alert) *gtfs.TranslatedString {
+	return translatedString(pa, alertFieldImageAlternativeText)
+}
+
+// SetAlertCauseDetail sets Alert.cause_detail; nil clears it.
+func SetAlertCauseDetail(pa *gtfs.Alert, ts *gtfs.TranslatedString) {
+	setTranslatedString(pa, alertFieldCauseDetail, ts)
+}
+
+// AlertCauseDetail returns Alert.cause_detail, or nil.
+func AlertCauseDetail(pa *gtfs.Alert) *gtfs.TranslatedString {
+	return translatedString(pa, alertFieldCauseDetail)
+}
+
+// SetAlertEffectDetail sets Alert.effect_detail; nil clears it.
+func SetAlertEffectDetail(pa *gtfs.Alert, ts *gtfs.TranslatedString) {
+	setTranslatedString(pa, alertFieldEffectDetail, ts)
+}
+
+// AlertEffectDetail returns Alert.effect_detail, or nil.
+func AlertEffectDetail(pa *gtfs.Alert) *gtfs.TranslatedString {
+	return translatedString(pa, alertFieldEffectDetail)
+}
+
+// SetTripHeadsign sets TripProperties.trip_headsign; "" clears it.
+func SetTripHeadsign(tp *gtfs.TripUpdate_TripProperties, headsign string) {
+	setUnknown(tp, tripPropertiesFieldTripHeadsign, []byte(headsign), headsign != "")
+}
+
+// TripHeadsign returns TripProperties.trip_headsign, or "".
+func TripHeadsign(tp *gtfs.TripUpdate_TripProperties) string {
+	if tp == nil {
+		return ""
+	}
+	values := unknownFields(tp, tripPropertiesFieldTripHeadsign)
+	if len(values) == 0 {
+		return ""
+	}
+	return string(values[len(values)-1])
+}
+
+func setTranslatedString(m proto.Message, num protowire.Number, ts *gtfs.TranslatedString) {
+	if ts == nil {
+		setUnknown(m, num, nil, false)
+		return
+	}
+	b, err := proto.Marshal(ts)
+	if err != nil {
+		return
+	}
+	setUnknown(m, num, b, true)
+}
+
+// translatedString merges every occurrence of the field, as protobuf does
+// for repeated embedded messages.
+func translatedString(m proto.Message, num protowire.Number) *gtfs.TranslatedString {
+	if m == nil {
+		return nil
+	}
+	values := unknownFields(m, num)
+	if len(values) == 0 {
+		return nil
+	}
+	ts := &gtfs.TranslatedString{}
+	for _, v := range values {
+		if err := (proto.UnmarshalOptions{Merge: true}).Unmarshal(v, ts); err != nil {
+			return nil
+		}
+	}
+	return ts
+}
+
+// setUnknown replaces the length-delimited unknown field num of m with value,
+// or removes it when set is false.
+func setUnknown(m proto.Message, num protowire.Number, value []byte, set bool) {
+	r := m.ProtoReflect()
+	raw := r.GetUnknown()
+	var kept []byte
+	for len(raw) > 0 {
+		n, typ, tl := protowire.ConsumeTag(raw)
+		if tl < 0 {
+			break
+		}
+		vl := protowire.ConsumeFieldValue(n, typ, raw[tl:])
+		if vl < 0 {
+			break
+		}
+		if n != num {
+			kept = append(kept, raw[:tl+vl]...)
+		}
+		raw = raw[tl+vl:]
+	}
+	if set {
+		kept = protowire.AppendTag(kept, num, protowire.BytesType)
+		kept = protowire.AppendBytes(kept, value)
+	}
+	r.SetUnknown(kept)
+}
+
+// unknownFields returns the values of the length-delimited unknown field num.
+func unknownFields(m proto.Message, num protowire.Number) [][]byte {
+	var out [][]byte
+	raw := m.ProtoReflect().GetUnknown()
+	for len(raw) > 0 {
+		n, typ, tl := protowire.ConsumeTag(raw)
+		if tl < 0 {
+			break
+		}
+		raw = raw[tl:]
+		if n == num && typ == protowire.BytesType {
+			v, vl := protowire.ConsumeBytes(raw)
+			if vl < 0 {
+				break
+			}
+			out = append(out, v)
+			raw = raw[vl:]
+			continue
+		}
+		vl := protowire.ConsumeFieldValue(n, typ, raw)
+		if vl < 0 {
+			break
+		}
+		raw = raw[vl:]
+	}
+	return out
+}
+
+// marshalTranslatedImage encodes TranslatedImage { repeated LocalizedImage
+// localized_image = 1 } with LocalizedImage { url = 1; media_type = 2; language = 3 }.
+func marshalTranslatedImage(img *TranslatedImage) []byte {
+	var out []byte
+	for _, li := range img.LocalizedImage {
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, li.Url)
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, li.MediaType)
+		if li.Language != nil {
+			b = protowire.AppendTag(b, 3, protowire.BytesType)
+			b = protowire.AppendString(b, *li.Language)
+		}
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		out = protowire.AppendBytes(out, b)
+	}
+	return out
+}
+
+func unmarshalLocalizedImages(b []byte) []LocalizedImage {
+	var out []LocalizedImage
+	for len(b) > 0 {
+		n, typ, tl := protowire.ConsumeTag(b)
+		if tl < 0 {
+			break
+		}
+		b = b[tl:]
+		if n != 1 || typ != protowire.BytesType {
+			vl := protowire.ConsumeFieldValue(n, typ, b)
+			if vl < 0 {
+				break
+			}
+			b = b[vl:]
+			continue
+		}
+		v, vl := protowire.ConsumeBytes(b)
+		if vl < 0 {
+			break
+		}
+		b = b[vl:]
+		out = append(out, unmarshalLocalizedImage(v))
+	}
+	return out
+}
+
+func unmarshalLocalizedImage(b []byte) LocalizedImage {
+	var li LocalizedImage
+	for len(b) > 0 {
+		n, typ, tl := protowire.ConsumeTag(b)
+		if tl < 0 {
+			break
+		}
+		b = b[tl:]
+		if typ != protowire.BytesType {
+			vl := protowire.ConsumeFieldValue(n, typ, b)
+			if vl < 0 {
+				break
+			}
+			b = b[vl:]
+			continue
+		}
+		v, vl := protowire.ConsumeString(b)
+		if vl < 0 {
+			break
+		}
+		b = b[vl:]
+		switch n {
+		case 1:
+			li.Url = v
+		case 2:
+			li.MediaType = v
+		case 3:
+			lang := v
+			li.Language = &lang
+		}
+	}
+	return li
+}
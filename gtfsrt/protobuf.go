@@ -1,7 +1,6 @@
 package gtfsrt
 
 import (
-	"fmt"
 	"strings"
 	"time"
 
@@ -17,18 +16,15 @@ func ToProto(m *FeedMessage) *gtfs.FeedMessage {
 	pm := &gtfs.FeedMessage{}
 	if m.Header != nil {
 		pm.Header = &gtfs.FeedHeader{}
-		if m.Header.Timestamp != "" {
-			// Parse string timestamp to uint64
-			var ts uint64
-			fmt.Sscanf(m.Header.Timestamp, "%d", &ts)
-			pm.Header.Timestamp = &ts
+		if m.Header.Timestamp != 0 {
+			pm.Header.Timestamp = proto.Uint64(m.Header.Timestamp)
 		} else {
 			// Ensure a header timestamp exists for consumer compatibility
 			ts := uint64(time.Now().Unix())
 			pm.Header.Timestamp = &ts
 		}
 		if m.Header.GtfsRealtimeVersion != nil {
-			pm.Header.GtfsRealtimeVersion = m.Header.GtfsRealtimeVersion
+			pm.Header.GtfsRealtimeVersion = proto.String(*m.Header.GtfsRealtimeVersion)
 		}
 		if m.Header.Incrementality != nil {
 			inc := gtfs.FeedHeader_Incrementality(*m.Header.Incrementality)
@@ -36,17 +32,19 @@ func ToProto(m *FeedMessage) *gtfs.FeedMessage {
 		}
 	}
 	for _, e := range m.Entity {
-		pm.Entity = append(pm.Entity, toProtoEntity(e))
+		pm.Entity = append(pm.Entity, ToProtoEntity(e))
 	}
 	return pm
 }
 
-func toProtoEntity(e *FeedEntity) *gtfs.FeedEntity {
+// ToProtoEntity converts a single internal FeedEntity to its protobuf form.
+func ToProtoEntity(e *FeedEntity) *gtfs.FeedEntity {
 	pe := &gtfs.FeedEntity{}
 	if e == nil {
 		return pe
 	}
-	pe.Id = e.Id
+	pe.Id = clonePtr(e.Id)
+	pe.IsDeleted = clonePtr(e.IsDeleted)
 	if e.TripUpdate != nil {
 		pe.TripUpdate = toProtoTripUpdate(e.TripUpdate)
 	}
@@ -60,84 +58,60 @@ func toProtoEntity(e *FeedEntity) *gtfs.FeedEntity {
 }
 
 func toProtoTripUpdate(tu *TripUpdate) *gtfs.TripUpdate {
-	ptu := &gtfs.TripUpdate{}
-	if tu.Timestamp != "" {
-		var ts uint64
-		fmt.Sscanf(tu.Timestamp, "%d", &ts)
-		ptu.Timestamp = &ts
+	ptu := &gtfs.TripUpdate{Delay: clonePtr(tu.Delay)}
+	if tu.Timestamp != 0 {
+		ptu.Timestamp = proto.Uint64(tu.Timestamp)
 	}
 	if tu.Trip != nil {
-		ptu.Trip = &gtfs.TripDescriptor{
-			TripId:    proto.String(tu.Trip.TripId),
-			RouteId:   proto.String(tu.Trip.RouteId),
-			StartDate: proto.String(tu.Trip.StartDate),
-			StartTime: proto.String(tu.Trip.StartTime),
-		}
-		if tu.Trip.ScheduleRelationship != nil {
-			sr := gtfs.TripDescriptor_ScheduleRelationship(*tu.Trip.ScheduleRelationship)
-			ptu.Trip.ScheduleRelationship = &sr
-		}
+		ptu.Trip = toProtoTripDescriptor(tu.Trip)
+	}
+	if tu.TripProperties != nil && *tu.TripProperties != (TripProperties{}) {
+		ptu.TripProperties = toProtoTripProperties(tu.TripProperties)
 	}
 	if tu.Vehicle != nil {
-		ptu.Vehicle = &gtfs.VehicleDescriptor{Id: proto.String(tu.Vehicle.Id)}
+		ptu.Vehicle = toProtoVehicleDescriptor(tu.Vehicle)
 	}
 	for _, stu := range tu.StopTimeUpdate {
-		ps := &gtfs.TripUpdate_StopTimeUpdate{StopId: proto.String(stu.StopId), StopSequence: proto.Uint32(uint32(stu.StopSequence))}
+		ps := &gtfs.TripUpdate_StopTimeUpdate{StopId: optionalString(stu.StopId), StopSequence: proto.Uint32(uint32(stu.StopSequence))}
 		if stu.Arrival != nil {
-			event := &gtfs.TripUpdate_StopTimeEvent{}
-			if stu.Arrival.Time != "" {
-				var t int64
-				fmt.Sscanf(stu.Arrival.Time, "%d", &t)
-				event.Time = proto.Int64(t)
-			}
-			if stu.Arrival.Delay != nil {
-				event.Delay = proto.Int32(*stu.Arrival.Delay)
-			}
-			if stu.Arrival.Uncertainty != nil {
-				event.Uncertainty = proto.Int32(*stu.Arrival.Uncertainty)
-			}
-			ps.Arrival = event
+			ps.Arrival = toProtoStopTimeEvent(stu.Arrival)
 		}
 		if stu.Departure != nil {
-			event := &gtfs.TripUpdate_StopTimeEvent{}
-			if stu.Departure.Time != "" {
-				var t int64
-				fmt.Sscanf(stu.Departure.Time, "%d", &t)
-				event.Time = proto.Int64(t)
-			}
-			if stu.Departure.Delay != nil {
-				event.Delay = proto.Int32(*stu.Departure.Delay)
-			}
-			if stu.Departure.Uncertainty != nil {
-				event.Uncertainty = proto.Int32(*stu.Departure.Uncertainty)
-			}
-			ps.Departure = event
+			ps.Departure = toProtoStopTimeEvent(stu.Departure)
 		}
 		if stu.ScheduleRelationship != nil {
 			sr := gtfs.TripUpdate_StopTimeUpdate_ScheduleRelationship(*stu.ScheduleRelationship)
 			ps.ScheduleRelationship = &sr
 		}
+		if stu.StopTimeProperties != nil {
+			ps.StopTimeProperties = &gtfs.TripUpdate_StopTimeUpdate_StopTimeProperties{AssignedStopId: optionalString(stu.StopTimeProperties.AssignedStopId)}
+		}
 		ptu.StopTimeUpdate = append(ptu.StopTimeUpdate, ps)
 	}
 	return ptu
 }
 
+func toProtoStopTimeEvent(ev *StopTimeEvent) *gtfs.TripUpdate_StopTimeEvent {
+	event := &gtfs.TripUpdate_StopTimeEvent{}
+	if ev.Time != 0 {
+		event.Time = proto.Int64(ev.Time)
+	}
+	if ev.Delay != nil {
+		event.Delay = proto.Int32(*ev.Delay)
+	}
+	if ev.Uncertainty != nil {
+		event.Uncertainty = proto.Int32(*ev.Uncertainty)
+	}
+	return event
+}
+
 func toProtoVehicle(v *VehiclePosition) *gtfs.VehiclePosition {
 	pv := &gtfs.VehiclePosition{}
 	if v.Trip != nil {
-		pv.Trip = &gtfs.TripDescriptor{
-			TripId:    proto.String(v.Trip.TripId),
-			RouteId:   proto.String(v.Trip.RouteId),
-			StartDate: proto.String(v.Trip.StartDate),
-			StartTime: proto.String(v.Trip.StartTime),
-		}
-		if v.Trip.ScheduleRelationship != nil {
-			sr := gtfs.TripDescriptor_ScheduleRelationship(*v.Trip.ScheduleRelationship)
-			pv.Trip.ScheduleRelationship = &sr
-		}
+		pv.Trip = toProtoTripDescriptor(v.Trip)
 	}
 	if v.Vehicle != nil {
-		pv.Vehicle = &gtfs.VehicleDescriptor{Id: proto.String(v.Vehicle.Id)}
+		pv.Vehicle = toProtoVehicleDescriptor(v.Vehicle)
 	}
 	if v.Position != nil {
 		pp := &gtfs.Position{Latitude: proto.Float32(v.Position.Latitude), Longitude: proto.Float32(v.Position.Longitude)}
@@ -152,10 +126,8 @@ func toProtoVehicle(v *VehiclePosition) *gtfs.VehiclePosition {
 		}
 		pv.Position = pp
 	}
-	if v.Timestamp != nil && *v.Timestamp != "" {
-		var ts uint64
-		fmt.Sscanf(*v.Timestamp, "%d", &ts)
-		pv.Timestamp = &ts
+	if v.Timestamp != nil && *v.Timestamp != 0 {
+		pv.Timestamp = proto.Uint64(*v.Timestamp)
 	}
 	if v.CurrentStatus != nil {
 		cs := gtfs.VehiclePosition_VehicleStopStatus(*v.CurrentStatus)
@@ -171,10 +143,24 @@ func toProtoVehicle(v *VehiclePosition) *gtfs.VehiclePosition {
 		os := gtfs.VehiclePosition_OccupancyStatus(*v.OccupancyStatus)
 		pv.OccupancyStatus = &os
 	}
+	pv.OccupancyPercentage = clonePtr(v.OccupancyPercentage)
 	if v.CongestionLevel != nil {
 		cl := gtfs.VehiclePosition_CongestionLevel(*v.CongestionLevel)
 		pv.CongestionLevel = &cl
 	}
+	for _, cd := range v.MultiCarriageDetails {
+		pcd := &gtfs.VehiclePosition_CarriageDetails{
+			Id:                  optionalString(cd.Id),
+			Label:               optionalString(cd.Label),
+			OccupancyPercentage: clonePtr(cd.OccupancyPercentage),
+			CarriageSequence:    clonePtr(cd.CarriageSequence),
+		}
+		if cd.OccupancyStatus != nil {
+			os := gtfs.VehiclePosition_OccupancyStatus(*cd.OccupancyStatus)
+			pcd.OccupancyStatus = &os
+		}
+		pv.MultiCarriageDetails = append(pv.MultiCarriageDetails, pcd)
+	}
 	return pv
 }
 
@@ -219,28 +205,89 @@ func toProtoAlert(a *Alert) *gtfs.Alert {
 	}
 	for _, ie := range a.InformedEntity {
 		pie := &gtfs.EntitySelector{}
+		if ie.AgencyId != nil {
+			pie.AgencyId = proto.String(*ie.AgencyId)
+		}
+		if ie.RouteType != nil {
+			pie.RouteType = proto.Int32(*ie.RouteType)
+		}
 		if ie.RouteId != nil {
 			pie.RouteId = proto.String(*ie.RouteId)
 		}
+		if ie.DirectionId != nil {
+			pie.DirectionId = proto.Uint32(*ie.DirectionId)
+		}
 		if ie.StopId != nil {
 			pie.StopId = proto.String(*ie.StopId)
 		}
 		if ie.Trip != nil {
-			pie.Trip = &gtfs.TripDescriptor{
-				TripId:    proto.String(ie.Trip.TripId),
-				RouteId:   proto.String(ie.Trip.RouteId),
-				StartDate: proto.String(ie.Trip.StartDate),
-				StartTime: proto.String(ie.Trip.StartTime),
-			}
+			pie.Trip = toProtoTripDescriptor(ie.Trip)
 		}
 		pa.InformedEntity = append(pa.InformedEntity, pie)
 	}
 	if a.Url != nil {
 		pa.Url = toProtoTranslatedString(a.Url)
 	}
+	if a.TtsHeaderText != nil {
+		pa.TtsHeaderText = toProtoTranslatedString(a.TtsHeaderText)
+	}
+	if a.TtsDescriptionText != nil {
+		pa.TtsDescriptionText = toProtoTranslatedString(a.TtsDescriptionText)
+	}
+	if a.SeverityLevel != nil {
+		sl := gtfs.Alert_SeverityLevel(*a.SeverityLevel)
+		pa.SeverityLevel = &sl
+	}
+	if a.Image != nil {
+		SetAlertImage(pa, a.Image)
+	}
+	if a.ImageAlternativeText != nil {
+		SetAlertImageAlternativeText(pa, toProtoTranslatedString(a.ImageAlternativeText))
+	}
+	if a.CauseDetail != nil {
+		SetAlertCauseDetail(pa, toProtoTranslatedString(a.CauseDetail))
+	}
+	if a.EffectDetail != nil {
+		SetAlertEffectDetail(pa, toProtoTranslatedString(a.EffectDetail))
+	}
 	return pa
 }
 
+func toProtoTripDescriptor(td *TripDescriptor) *gtfs.TripDescriptor {
+	ptd := &gtfs.TripDescriptor{
+		TripId:    optionalString(td.TripId),
+		RouteId:   optionalString(td.RouteId),
+		StartDate: optionalString(td.StartDate),
+		StartTime: optionalString(td.StartTime),
+	}
+	if td.ScheduleRelationship != nil {
+		sr := gtfs.TripDescriptor_ScheduleRelationship(*td.ScheduleRelationship)
+		ptd.ScheduleRelationship = &sr
+	}
+	if td.DirectionId != nil {
+		ptd.DirectionId = proto.Uint32(*td.DirectionId)
+	}
+	return ptd
+}
+
+func toProtoVehicleDescriptor(vd *VehicleDescriptor) *gtfs.VehicleDescriptor {
+	return &gtfs.VehicleDescriptor{
+		Id:           proto.String(vd.Id),
+		Label:        optionalString(vd.Label),
+		LicensePlate: optionalString(vd.LicensePlate),
+	}
+}
+
+func toProtoTripProperties(tp *TripProperties) *gtfs.TripUpdate_TripProperties {
+	ptp := &gtfs.TripUpdate_TripProperties{
+		TripId:    optionalString(tp.TripId),
+		StartDate: optionalString(tp.StartDate),
+		StartTime: optionalString(tp.StartTime),
+	}
+	SetTripHeadsign(ptp, tp.TripHeadsign)
+	return ptp
+}
+
 func toProtoTranslatedString(ts *TranslatedString) *gtfs.TranslatedString {
 	pts := &gtfs.TranslatedString{}
 	for _, tr := range ts.Translation {
@@ -259,6 +306,220 @@ func MarshalPBF(m *FeedMessage) ([]byte, error) {
 	return proto.Marshal(pm)
 }
 
+// FromProto derives the internal JSON view of a protobuf FeedMessage,
+// including the fields newer than the bindings.
+func FromProto(pm *gtfs.FeedMessage) *FeedMessage {
+	if pm == nil {
+		return nil
+	}
+	m := &FeedMessage{}
+	if h := pm.Header; h != nil {
+		m.Header = &FeedHeader{
+			GtfsRealtimeVersion: clonePtr(h.GtfsRealtimeVersion),
+			Incrementality:      enumValue(h.Incrementality),
+			Timestamp:           h.GetTimestamp(),
+		}
+	}
+	for _, pe := range pm.Entity {
+		m.Entity = append(m.Entity, FromProtoEntity(pe))
+	}
+	return m
+}
+
+// FromProtoEntity derives the internal JSON view of a single protobuf
+// FeedEntity.
+func FromProtoEntity(pe *gtfs.FeedEntity) *FeedEntity {
+	e := &FeedEntity{}
+	if pe == nil {
+		return e
+	}
+	e.Id = clonePtr(pe.Id)
+	e.IsDeleted = clonePtr(pe.IsDeleted)
+	if pe.TripUpdate != nil {
+		e.TripUpdate = fromProtoTripUpdate(pe.TripUpdate)
+	}
+	if pe.Vehicle != nil {
+		e.Vehicle = fromProtoVehicle(pe.Vehicle)
+	}
+	if pe.Alert != nil {
+		e.Alert = fromProtoAlert(pe.Alert)
+	}
+	return e
+}
+
+func fromProtoTripUpdate(ptu *gtfs.TripUpdate) *TripUpdate {
+	tu := &TripUpdate{Timestamp: ptu.GetTimestamp(), Delay: clonePtr(ptu.Delay)}
+	if ptu.Trip != nil {
+		tu.Trip = fromProtoTripDescriptor(ptu.Trip)
+	}
+	if ptu.Vehicle != nil {
+		tu.Vehicle = fromProtoVehicleDescriptor(ptu.Vehicle)
+	}
+	if ptp := ptu.TripProperties; ptp != nil {
+		tu.TripProperties = &TripProperties{
+			TripId:       ptp.GetTripId(),
+			StartDate:    ptp.GetStartDate(),
+			StartTime:    ptp.GetStartTime(),
+			TripHeadsign: TripHeadsign(ptp),
+		}
+	}
+	for _, ps := range ptu.StopTimeUpdate {
+		stu := StopTimeUpdate{
+			ScheduleRelationship: enumValue(ps.ScheduleRelationship),
+			StopId:               ps.GetStopId(),
+			StopSequence:         int32(ps.GetStopSequence()),
+		}
+		if ps.Arrival != nil {
+			stu.Arrival = fromProtoStopTimeEvent(ps.Arrival)
+		}
+		if ps.Departure != nil {
+			stu.Departure = fromProtoStopTimeEvent(ps.Departure)
+		}
+		if ps.StopTimeProperties != nil {
+			stu.StopTimeProperties = &StopTimeProperties{AssignedStopId: ps.StopTimeProperties.GetAssignedStopId()}
+		}
+		tu.StopTimeUpdate = append(tu.StopTimeUpdate, stu)
+	}
+	return tu
+}
+
+func fromProtoStopTimeEvent(ev *gtfs.TripUpdate_StopTimeEvent) *StopTimeEvent {
+	return &StopTimeEvent{Time: ev.GetTime(), Uncertainty: clonePtr(ev.Uncertainty), Delay: clonePtr(ev.Delay)}
+}
+
+func fromProtoVehicle(pv *gtfs.VehiclePosition) *VehiclePosition {
+	v := &VehiclePosition{
+		CongestionLevel:     enumValue(pv.CongestionLevel),
+		CurrentStatus:       enumValue(pv.CurrentStatus),
+		OccupancyStatus:     enumValue(pv.OccupancyStatus),
+		OccupancyPercentage: clonePtr(pv.OccupancyPercentage),
+		StopId:              clonePtr(pv.StopId),
+		Timestamp:           clonePtr(pv.Timestamp),
+	}
+	if pv.CurrentStopSequence != nil {
+		seq := int32(*pv.CurrentStopSequence)
+		v.CurrentStopSequence = &seq
+	}
+	if pp := pv.Position; pp != nil {
+		v.Position = &Position{
+			Latitude:  pp.GetLatitude(),
+			Longitude: pp.GetLongitude(),
+			Bearing:   clonePtr(pp.Bearing),
+			Speed:     clonePtr(pp.Speed),
+			Odometer:  clonePtr(pp.Odometer),
+		}
+	}
+	if pv.Trip != nil {
+		v.Trip = fromProtoTripDescriptor(pv.Trip)
+	}
+	if pv.Vehicle != nil {
+		v.Vehicle = fromProtoVehicleDescriptor(pv.Vehicle)
+	}
+	for _, pcd := range pv.MultiCarriageDetails {
+		v.MultiCarriageDetails = append(v.MultiCarriageDetails, CarriageDetails{
+			Id:                  pcd.GetId(),
+			Label:               pcd.GetLabel(),
+			OccupancyStatus:     enumValue(pcd.OccupancyStatus),
+			OccupancyPercentage: clonePtr(pcd.OccupancyPercentage),
+			CarriageSequence:    clonePtr(pcd.CarriageSequence),
+		})
+	}
+	return v
+}
+
+func fromProtoAlert(pa *gtfs.Alert) *Alert {
+	a := &Alert{
+		Cause:                enumValue(pa.Cause),
+		Effect:               enumValue(pa.Effect),
+		SeverityLevel:        enumValue(pa.SeverityLevel),
+		HeaderText:           fromProtoTranslatedString(pa.HeaderText),
+		DescriptionText:      fromProtoTranslatedString(pa.DescriptionText),
+		Url:                  fromProtoTranslatedString(pa.Url),
+		TtsHeaderText:        fromProtoTranslatedString(pa.TtsHeaderText),
+		TtsDescriptionText:   fromProtoTranslatedString(pa.TtsDescriptionText),
+		Image:                AlertImage(pa),
+		ImageAlternativeText: fromProtoTranslatedString(AlertImageAlternativeText(pa)),
+		CauseDetail:          fromProtoTranslatedString(AlertCauseDetail(pa)),
+		EffectDetail:         fromProtoTranslatedString(AlertEffectDetail(pa)),
+	}
+	for _, ptr := range pa.ActivePeriod {
+		var tr TimeRange
+		if ptr.Start != nil {
+			s := int64(*ptr.Start)
+			tr.Start = &s
+		}
+		if ptr.End != nil {
+			e := int64(*ptr.End)
+			tr.End = &e
+		}
+		a.ActivePeriod = append(a.ActivePeriod, tr)
+	}
+	for _, pie := range pa.InformedEntity {
+		sel := EntitySelector{
+			AgencyId:    clonePtr(pie.AgencyId),
+			RouteType:   clonePtr(pie.RouteType),
+			RouteId:     clonePtr(pie.RouteId),
+			DirectionId: clonePtr(pie.DirectionId),
+			StopId:      clonePtr(pie.StopId),
+		}
+		if pie.Trip != nil {
+			sel.Trip = fromProtoTripDescriptor(pie.Trip)
+		}
+		a.InformedEntity = append(a.InformedEntity, sel)
+	}
+	return a
+}
+
+func fromProtoTripDescriptor(ptd *gtfs.TripDescriptor) *TripDescriptor {
+	return &TripDescriptor{
+		RouteId:              ptd.GetRouteId(),
+		ScheduleRelationship: enumValue(ptd.ScheduleRelationship),
+		TripId:               ptd.GetTripId(),
+		StartDate:            ptd.GetStartDate(),
+		StartTime:            ptd.GetStartTime(),
+		DirectionId:          clonePtr(ptd.DirectionId),
+	}
+}
+
+func fromProtoVehicleDescriptor(pvd *gtfs.VehicleDescriptor) *VehicleDescriptor {
+	return &VehicleDescriptor{Id: pvd.GetId(), Label: pvd.GetLabel(), LicensePlate: pvd.GetLicensePlate()}
+}
+
+func fromProtoTranslatedString(pts *gtfs.TranslatedString) *TranslatedString {
+	if pts == nil {
+		return nil
+	}
+	ts := &TranslatedString{}
+	for _, pt := range pts.Translation {
+		ts.Translation = append(ts.Translation, Translation{Text: pt.GetText(), Language: clonePtr(pt.Language)})
+	}
+	return ts
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// enumValue returns a protobuf enum as the int32 used by the internal types.
+func enumValue[E ~int32](p *E) *int32 {
+	if p == nil {
+		return nil
+	}
+	v := int32(*p)
+	return &v
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
 // Mapping helpers from internal strings (from SIRI) to GTFS-RT enums
 func mapVehicleStopStatus(s string) *gtfs.VehiclePosition_VehicleStopStatus {
 	switch normalize(s) {
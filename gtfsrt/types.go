@@ -1,21 +1,24 @@
 package gtfsrt
 
-import (
-	"fmt"
-	"time"
-)
+import "time"
 
-// NOTE: Placeholder GTFS-RT-like types to allow compilation and JSON output
-// before wiring real protobuf bindings. Replace with MobilityData bindings later.
+// JSON view of GTFS-RT messages. The converter builds MobilityData protobuf
+// messages; FromProto derives these types from them, fields newer than the
+// bindings included, and ToProto converts them back.
+//
+// Timestamps are POSIX seconds held as numbers; like protobuf JSON, their
+// 64-bit values are written as quoted strings and a zero value means unset.
 
 type FeedHeader struct {
 	GtfsRealtimeVersion *string `json:"gtfs_realtime_version,omitempty"`
 	Incrementality      *int32  `json:"incrementality,omitempty"`
-	Timestamp           string  `json:"timestamp,omitempty"`
+	Timestamp           uint64  `json:"timestamp,omitempty,string"`
 }
 
+// FeedHeader.incrementality values.
 const (
-	IncrementalityFullDataset int32 = 1
+	IncrementalityFullDataset  int32 = 0
+	IncrementalityDifferential int32 = 1
 )
 
 type FeedMessage struct {
@@ -35,33 +38,52 @@ type FeedEntity struct {
 
 type TripUpdate struct {
 	StopTimeUpdate []StopTimeUpdate   `json:"stop_time_update,omitempty"`
-	Timestamp      string             `json:"timestamp,omitempty"`
+	Timestamp      uint64             `json:"timestamp,omitempty,string"`
 	Trip           *TripDescriptor    `json:"trip,omitempty"`
 	Vehicle        *VehicleDescriptor `json:"vehicle,omitempty"`
+	Delay          *int32             `json:"delay,omitempty"`
+	TripProperties *TripProperties    `json:"trip_properties,omitempty"`
+}
+
+// TripProperties carries trip metadata not present in the static GTFS, such
+// as the headsign of an ADDED trip.
+type TripProperties struct {
+	TripId       string `json:"trip_id,omitempty"`
+	StartDate    string `json:"start_date,omitempty"`
+	StartTime    string `json:"start_time,omitempty"`
+	TripHeadsign string `json:"trip_headsign,omitempty"`
 }
 
 type TripDescriptor struct {
-	RouteId              string `json:"route_id,omitempty"`
-	ScheduleRelationship *int32 `json:"schedule_relationship,omitempty"`
-	TripId               string `json:"trip_id,omitempty"`
-	StartDate            string `json:"start_date,omitempty"`
-	StartTime            string `json:"start_time,omitempty"`
+	RouteId              string  `json:"route_id,omitempty"`
+	ScheduleRelationship *int32  `json:"schedule_relationship,omitempty"`
+	TripId               string  `json:"trip_id,omitempty"`
+	StartDate            string  `json:"start_date,omitempty"`
+	StartTime            string  `json:"start_time,omitempty"`
+	DirectionId          *uint32 `json:"direction_id,omitempty"`
 }
 
 type VehicleDescriptor struct {
-	Id string `json:"id,omitempty"`
+	Id           string `json:"id,omitempty"`
+	Label        string `json:"label,omitempty"`
+	LicensePlate string `json:"license_plate,omitempty"`
 }
 
 type StopTimeUpdate struct {
-	Arrival              *StopTimeEvent `json:"arrival,omitempty"`
-	Departure            *StopTimeEvent `json:"departure,omitempty"`
-	ScheduleRelationship *int32         `json:"schedule_relationship,omitempty"`
-	StopId               string         `json:"stop_id,omitempty"`
-	StopSequence         int32          `json:"stop_sequence"`
+	Arrival              *StopTimeEvent      `json:"arrival,omitempty"`
+	Departure            *StopTimeEvent      `json:"departure,omitempty"`
+	ScheduleRelationship *int32              `json:"schedule_relationship,omitempty"`
+	StopId               string              `json:"stop_id,omitempty"`
+	StopSequence         int32               `json:"stop_sequence"`
+	StopTimeProperties   *StopTimeProperties `json:"stop_time_properties,omitempty"`
+}
+
+type StopTimeProperties struct {
+	AssignedStopId string `json:"assigned_stop_id,omitempty"`
 }
 
 type StopTimeEvent struct {
-	Time        string `json:"time,omitempty"`
+	Time        int64  `json:"time,omitempty,string"`
 	Uncertainty *int32 `json:"uncertainty,omitempty"`
 	Delay       *int32 `json:"delay,omitempty"`
 }
@@ -69,15 +91,25 @@ type StopTimeEvent struct {
 // VehiclePosition
 
 type VehiclePosition struct {
-	CongestionLevel     *int32             `json:"congestion_level,omitempty"`
-	CurrentStatus       *int32             `json:"current_status,omitempty"`
-	CurrentStopSequence *int32             `json:"current_stop_sequence,omitempty"`
-	OccupancyStatus     *int32             `json:"occupancy_status,omitempty"`
-	Position            *Position          `json:"position,omitempty"`
-	StopId              *string            `json:"stop_id,omitempty"`
-	Timestamp           *string            `json:"timestamp,omitempty"`
-	Trip                *TripDescriptor    `json:"trip,omitempty"`
-	Vehicle             *VehicleDescriptor `json:"vehicle,omitempty"`
+	CongestionLevel      *int32             `json:"congestion_level,omitempty"`
+	CurrentStatus        *int32             `json:"current_status,omitempty"`
+	CurrentStopSequence  *int32             `json:"current_stop_sequence,omitempty"`
+	OccupancyStatus      *int32             `json:"occupancy_status,omitempty"`
+	OccupancyPercentage  *uint32            `json:"occupancy_percentage,omitempty"`
+	MultiCarriageDetails []CarriageDetails  `json:"multi_carriage_details,omitempty"`
+	Position             *Position          `json:"position,omitempty"`
+	StopId               *string            `json:"stop_id,omitempty"`
+	Timestamp            *uint64            `json:"timestamp,omitempty,string"`
+	Trip                 *TripDescriptor    `json:"trip,omitempty"`
+	Vehicle              *VehicleDescriptor `json:"vehicle,omitempty"`
+}
+
+type CarriageDetails struct {
+	Id                  string  `json:"id,omitempty"`
+	Label               string  `json:"label,omitempty"`
+	OccupancyStatus     *int32  `json:"occupancy_status,omitempty"`
+	OccupancyPercentage *int32  `json:"occupancy_percentage,omitempty"`
+	CarriageSequence    *uint32 `json:"carriage_sequence,omitempty"`
 }
 
 type Position struct {
@@ -98,8 +130,24 @@ type Alert struct {
 	HeaderText      *TranslatedString `json:"header_text,omitempty"`
 	InformedEntity  []EntitySelector  `json:"informed_entity,omitempty"`
 	Url             *TranslatedString `json:"url,omitempty"`
-	// Raw SIRI fields for PBF mapping (not emitted in JSON)
-	Severity *string `json:"-"`
+
+	TtsHeaderText        *TranslatedString `json:"tts_header_text,omitempty"`
+	TtsDescriptionText   *TranslatedString `json:"tts_description_text,omitempty"`
+	Image                *TranslatedImage  `json:"image,omitempty"`
+	ImageAlternativeText *TranslatedString `json:"image_alternative_text,omitempty"`
+	CauseDetail          *TranslatedString `json:"cause_detail,omitempty"`
+	EffectDetail         *TranslatedString `json:"effect_detail,omitempty"`
+	SeverityLevel        *int32            `json:"severity_level,omitempty"`
+}
+
+type TranslatedImage struct {
+	LocalizedImage []LocalizedImage `json:"localized_image,omitempty"`
+}
+
+type LocalizedImage struct {
+	Url       string  `json:"url"`
+	MediaType string  `json:"media_type"`
+	Language  *string `json:"language,omitempty"`
 }
 
 type TranslatedString struct {
@@ -117,25 +165,37 @@ type TimeRange struct {
 }
 
 type EntitySelector struct {
-	RouteId *string         `json:"route_id,omitempty"`
-	StopId  *string         `json:"stop_id,omitempty"`
-	Trip    *TripDescriptor `json:"trip,omitempty"`
+	AgencyId    *string         `json:"agency_id,omitempty"`
+	RouteType   *int32          `json:"route_type,omitempty"`
+	RouteId     *string         `json:"route_id,omitempty"`
+	DirectionId *uint32         `json:"direction_id,omitempty"`
+	StopId      *string         `json:"stop_id,omitempty"`
+	Trip        *TripDescriptor `json:"trip,omitempty"`
 }
 
 // NewFeedMessageHeader creates a GTFS-RT header matching Java defaults.
 func NewFeedMessageHeader() *FeedHeader {
-	ts := fmt.Sprintf("%d", time.Now().Unix())
 	hdr := &FeedHeader{
-		Timestamp:           ts,
+		Timestamp:           uint64(time.Now().Unix()),
 		GtfsRealtimeVersion: stringPtr("2.0"),
-		Incrementality:      &[]int32{0}[0], // FULL_DATASET = 0
+		Incrementality:      int32Ptr(IncrementalityFullDataset),
 	}
 	return hdr
 }
 
+// NewDifferentialFeedMessageHeader is NewFeedMessageHeader for a feed that
+// carries only the entities changed since an earlier version.
+func NewDifferentialFeedMessageHeader() *FeedHeader {
+	hdr := NewFeedMessageHeader()
+	hdr.Incrementality = int32Ptr(IncrementalityDifferential)
+	return hdr
+}
+
 // NewFeedMessage returns a FeedMessage with a populated header.
 func NewFeedMessage() *FeedMessage {
 	return &FeedMessage{Header: NewFeedMessageHeader()}
 }
 
 func stringPtr(s string) *string { return &s }
+
+func int32Ptr(v int32) *int32 { return &v }
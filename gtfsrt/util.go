@@ -1,6 +1,7 @@
 package gtfsrt
 
 import (
+	"bytes"
 	"encoding/json"
 
 	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
@@ -32,3 +33,56 @@ func ProtoToJSON(pb any) ([]byte, error) {
 	}
 	return marshaler.Marshal(m)
 }
+
+// MarshalProtoJSON renders a protobuf FeedMessage as indented GTFS-RT JSON
+// with the field names of the .proto file. protojson cannot render unknown
+// fields, so the fields newer than the bindings (Alert image,
+// image_alternative_text, cause_detail and effect_detail, TripProperties
+// trip_headsign) are added to its output.
+func MarshalProtoJSON(pm *gtfs.FeedMessage) ([]byte, error) {
+	marshaler := protojson.MarshalOptions{
+		UseProtoNames:  true,
+		UseEnumNumbers: true,
+	}
+	b, err := marshaler.Marshal(pm)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	entities, _ := doc["entity"].([]any)
+	for i, pe := range pm.GetEntity() {
+		if i >= len(entities) {
+			break
+		}
+		ent, _ := entities[i].(map[string]any)
+		if pa := pe.GetAlert(); pa != nil {
+			if alert, ok := ent["alert"].(map[string]any); ok {
+				addExtension(alert, "image", AlertImage(pa))
+				addExtension(alert, "image_alternative_text", fromProtoTranslatedString(AlertImageAlternativeText(pa)))
+				addExtension(alert, "cause_detail", fromProtoTranslatedString(AlertCauseDetail(pa)))
+				addExtension(alert, "effect_detail", fromProtoTranslatedString(AlertEffectDetail(pa)))
+			}
+		}
+		if h := TripHeadsign(pe.GetTripUpdate().GetTripProperties()); h != "" {
+			if tu, ok := ent["trip_update"].(map[string]any); ok {
+				if tp, ok := tu["trip_properties"].(map[string]any); ok {
+					tp["trip_headsign"] = h
+				}
+			}
+		}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// addExtension sets key to v, which marshals like its protobuf message
+// because the internal types carry the .proto field names.
+func addExtension[T any](m map[string]any, key string, v *T) {
+	if v != nil {
+		m[key] = v
+	}
+}
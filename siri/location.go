@@ -0,0 +1,189 @@
+package siri
+
+import (
+	"encoding/xml"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Location decoding and projection helpers
+
+// Location is a SIRI LocationStructure. Producers either send WGS84
+// Longitude/Latitude children or a GML-style Coordinates/pos element whose
+// reference system is given by the srsName attribute. Projected coordinates
+// are converted to WGS84 when decoded so downstream code can rely on
+// Latitude/Longitude.
+type Location struct {
+	Longitude   float64 `xml:"Longitude"`
+	Latitude    float64 `xml:"Latitude"`
+	SrsName     string  `xml:"srsName,attr,omitempty"`
+	Coordinates *string `xml:"Coordinates"`
+	Pos         *string `xml:"pos"`
+}
+
+// UnmarshalXML decodes the location and resolves Coordinates/pos to WGS84.
+func (l *Location) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain Location
+	var p plain
+	if err := d.DecodeElement(&p, &start); err != nil {
+		return err
+	}
+	*l = Location(p)
+	l.Resolve()
+	return nil
+}
+
+// Resolve fills Latitude/Longitude from Coordinates or pos when they are not
+// already set. It reports whether the location holds usable WGS84 values.
+// Unsupported reference systems leave the location unchanged.
+func (l *Location) Resolve() bool {
+	if l.Latitude != 0 || l.Longitude != 0 {
+		return true
+	}
+	raw := l.Coordinates
+	if raw == nil {
+		raw = l.Pos
+	}
+	if raw == nil {
+		return false
+	}
+	x, y, ok := parseCoordinatePair(*raw)
+	if !ok {
+		return false
+	}
+	lat, lon, ok := ProjectToWGS84(l.SrsName, x, y)
+	if !ok {
+		return false
+	}
+	l.Latitude, l.Longitude = lat, lon
+	return true
+}
+
+// parseCoordinatePair reads "x y" or "x,y" into two floats.
+func parseCoordinatePair(s string) (float64, float64, bool) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	if len(fields) < 2 {
+		return 0, 0, false
+	}
+	x, err1 := strconv.ParseFloat(fields[0], 64)
+	y, err2 := strconv.ParseFloat(fields[1], 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// EPSGCode extracts the numeric EPSG code from common srsName notations such
+// as "EPSG:3857", "urn:ogc:def:crs:EPSG::3857" and
+// "http://www.opengis.net/def/crs/EPSG/0/3857". CRS84 maps to 4326 with
+// lon/lat axis order reported through the second return value.
+func EPSGCode(srsName string) (code int, lonLat bool) {
+	s := strings.TrimSpace(srsName)
+	if s == "" {
+		return 4326, true
+	}
+	upper := strings.ToUpper(s)
+	if strings.HasSuffix(upper, "CRS84") || upper == "WGS84" {
+		return 4326, true
+	}
+	i := strings.LastIndexAny(s, ":/#")
+	n, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return 0, false
+	}
+	return n, false
+}
+
+// ProjectToWGS84 converts the first/second coordinate of a point in the given
+// reference system to WGS84 latitude/longitude. Supported systems are
+// WGS84 (4326, CRS84), Web Mercator (3857), ETRS89 / UTM 32N and 33N
+// (25832, 25833), SWEREF99 TM (3006) and Swiss CH1903+ / LV95 (2056).
+func ProjectToWGS84(srsName string, first, second float64) (lat, lon float64, ok bool) {
+	code, lonLat := EPSGCode(srsName)
+	switch code {
+	case 4326:
+		if lonLat {
+			return second, first, true
+		}
+		// EPSG:4326 axis order is latitude, longitude
+		return first, second, true
+	case 3857, 900913:
+		lat, lon = fromWebMercator(first, second)
+	case 25832:
+		lat, lon = fromTransverseMercator(first, second, 9)
+	case 25833:
+		lat, lon = fromTransverseMercator(first, second, 15)
+	case 3006:
+		// EPSG axis order is northing, easting but many producers send
+		// easting first; northings in Sweden are always the larger value.
+		e, n := first, second
+		if e > n {
+			e, n = n, e
+		}
+		lat, lon = fromTransverseMercator(e, n, 15)
+	case 2056:
+		lat, lon = fromLV95(first, second)
+	default:
+		return 0, 0, false
+	}
+	if math.IsNaN(lat) || math.IsNaN(lon) {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+const (
+	grs80A = 6378137.0
+	grs80F = 1 / 298.257222101
+)
+
+func fromWebMercator(x, y float64) (lat, lon float64) {
+	lon = x / grs80A * 180 / math.Pi
+	lat = (2*math.Atan(math.Exp(y/grs80A)) - math.Pi/2) * 180 / math.Pi
+	return lat, lon
+}
+
+// fromTransverseMercator inverts a UTM-style projection (scale 0.9996, false
+// easting 500000) on the GRS80 ellipsoid. ETRS89 and SWEREF99 are treated as
+// identical to WGS84, which is well within GPS accuracy.
+func fromTransverseMercator(easting, northing, centralMeridian float64) (lat, lon float64) {
+	const k0 = 0.9996
+	e2 := grs80F * (2 - grs80F)
+	ep2 := e2 / (1 - e2)
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+
+	m := northing / k0
+	mu := m / (grs80A * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+	phi1 := mu +
+		(3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+		(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+		(151*math.Pow(e1, 3)/96)*math.Sin(6*mu) +
+		(1097*math.Pow(e1, 4)/512)*math.Sin(8*mu)
+
+	sin1, cos1, tan1 := math.Sin(phi1), math.Cos(phi1), math.Tan(phi1)
+	c1 := ep2 * cos1 * cos1
+	t1 := tan1 * tan1
+	n1 := grs80A / math.Sqrt(1-e2*sin1*sin1)
+	r1 := grs80A * (1 - e2) / math.Pow(1-e2*sin1*sin1, 1.5)
+	d := (easting - 500000) / (n1 * k0)
+
+	latRad := phi1 - (n1*tan1/r1)*(d*d/2-
+		(5+3*t1+10*c1-4*c1*c1-9*ep2)*math.Pow(d, 4)/24+
+		(61+90*t1+298*c1+45*t1*t1-252*ep2-3*c1*c1)*math.Pow(d, 6)/720)
+	lonRad := (d - (1+2*t1+c1)*math.Pow(d, 3)/6 +
+		(5-2*c1+28*t1-3*c1*c1+8*ep2+24*t1*t1)*math.Pow(d, 5)/120) / cos1
+
+	return latRad * 180 / math.Pi, centralMeridian + lonRad*180/math.Pi
+}
+
+// fromLV95 uses the swisstopo approximate formulas (accuracy around 1 m).
+func fromLV95(easting, northing float64) (lat, lon float64) {
+	y := (easting - 2600000) / 1e6
+	x := (northing - 1200000) / 1e6
+	lonSec := 2.6779094 + 4.728982*y + 0.791484*y*x + 0.1306*y*x*x - 0.0436*y*y*y
+	latSec := 16.9023892 + 3.238272*x - 0.270978*y*y - 0.002528*x*x - 0.0447*y*y*x - 0.0140*x*x*x
+	return latSec * 100 / 36, lonSec * 100 / 36
+}
@@ -1,6 +1,10 @@
 package siri
 
-import "time"
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
 
 // Time helpers
 
@@ -39,3 +43,30 @@ func Latest(t1, t2 time.Time) time.Time {
 
 // FormatDateYYYYMMDD formats a time into YYYYMMDD string.
 func FormatDateYYYYMMDD(t time.Time) string { return t.Format("20060102") }
+
+var xsdDuration = regexp.MustCompile(`^(-)?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseDuration parses an xsd:duration such as "PT5M", "-PT1M30S" or
+// "P0DT1H". Years and months are approximated as 365 and 30 days.
+func ParseDuration(s string) (time.Duration, bool) {
+	m := xsdDuration.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "-P" || s[len(s)-1] == 'T' {
+		return 0, false
+	}
+	units := []time.Duration{365 * 24 * time.Hour, 30 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] != "" {
+			n, _ := strconv.Atoi(m[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[7] != "" {
+		sec, _ := strconv.ParseFloat(m[7], 64)
+		d += time.Duration(sec * float64(time.Second))
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, true
+}
@@ -6,15 +6,23 @@ package siri
 // - Vehicle Monitoring (VM)
 // - Estimated Timetable (ET)
 // - Situation Exchange (SX)
+// - Facility Monitoring (FM)
+// - Stop Monitoring (SM)
+// - Production Timetable (PT)
+// - Connection Monitoring (CM)
 //
 // No business logic or I/O operations are performed here.
 
 // ServiceDelivery and SIRI domain types
 
 type ServiceDelivery struct {
-	EstimatedTimetableDeliveries []EstimatedTimetableDelivery `xml:"EstimatedTimetableDelivery"`
-	VehicleMonitoringDeliveries  []VehicleMonitoringDelivery  `xml:"VehicleMonitoringDelivery"`
-	SituationExchangeDeliveries  []SituationExchangeDelivery  `xml:"SituationExchangeDelivery"`
+	EstimatedTimetableDeliveries         []EstimatedTimetableDelivery         `xml:"EstimatedTimetableDelivery"`
+	VehicleMonitoringDeliveries          []VehicleMonitoringDelivery          `xml:"VehicleMonitoringDelivery"`
+	SituationExchangeDeliveries          []SituationExchangeDelivery          `xml:"SituationExchangeDelivery"`
+	FacilityMonitoringDeliveries         []FacilityMonitoringDelivery         `xml:"FacilityMonitoringDelivery"`
+	StopMonitoringDeliveries             []StopMonitoringDelivery             `xml:"StopMonitoringDelivery"`
+	ProductionTimetableDeliveries        []ProductionTimetableDelivery        `xml:"ProductionTimetableDelivery"`
+	ConnectionMonitoringFeederDeliveries []ConnectionMonitoringFeederDelivery `xml:"ConnectionMonitoringFeederDelivery"`
 }
 
 // Vehicle Monitoring (VM)
@@ -44,21 +52,75 @@ type MonitoredVehicleJourney struct {
 	Velocity                 *float64                 `xml:"Velocity"`
 	Occupancy                *string                  `xml:"Occupancy"`
 	InCongestion             *bool                    `xml:"InCongestion"`
+	Delay                    *string                  `xml:"Delay"` // xsd:duration, e.g. PT5M or -PT30S
 	MonitoredCall            *MonitoredCall           `xml:"MonitoredCall"`
 	FramedVehicleJourneyRef  *FramedVehicleJourneyRef `xml:"FramedVehicleJourneyRef"`
 	OriginAimedDepartureTime *string                  `xml:"OriginAimedDepartureTime"`
 }
 
-type Location struct {
-	Longitude float64 `xml:"Longitude"`
-	Latitude  float64 `xml:"Latitude"`
-}
-
 type MonitoredCall struct {
 	StopPointRef          *string   `xml:"StopPointRef"`
 	VehicleAtStop         *bool     `xml:"VehicleAtStop"`
 	VehicleLocationAtStop *Location `xml:"VehicleLocationAtStop"`
 	Order                 *int32    `xml:"Order"`
+	AimedArrivalTime      *string   `xml:"AimedArrivalTime"`
+	ExpectedArrivalTime   *string   `xml:"ExpectedArrivalTime"`
+	ActualArrivalTime     *string   `xml:"ActualArrivalTime"`
+	AimedDepartureTime    *string   `xml:"AimedDepartureTime"`
+	ExpectedDepartureTime *string   `xml:"ExpectedDepartureTime"`
+	ActualDepartureTime   *string   `xml:"ActualDepartureTime"`
+}
+
+// Stop Monitoring (SM)
+
+type StopMonitoringDelivery struct {
+	MonitoredStopVisits []MonitoredStopVisit `xml:"MonitoredStopVisit"`
+}
+
+// MonitoredStopVisit is the visit of one journey at the monitored stop; its
+// MonitoredCall carries the times at that stop.
+type MonitoredStopVisit struct {
+	RecordedAtTime          *string                  `xml:"RecordedAtTime"`
+	ValidUntilTime          *string                  `xml:"ValidUntilTime"`
+	MonitoringRef           *string                  `xml:"MonitoringRef"`
+	MonitoredVehicleJourney *MonitoredVehicleJourney `xml:"MonitoredVehicleJourney"`
+}
+
+// Production Timetable (PT)
+
+type ProductionTimetableDelivery struct {
+	DatedTimetableVersionFrames []DatedTimetableVersionFrame `xml:"DatedTimetableVersionFrame"`
+}
+
+// DatedTimetableVersionFrame groups the planned journeys of one line and
+// direction; journeys may override both.
+type DatedTimetableVersionFrame struct {
+	LineRef              *string               `xml:"LineRef"`
+	DirectionRef         *string               `xml:"DirectionRef"`
+	DatedVehicleJourneys []DatedVehicleJourney `xml:"DatedVehicleJourney"`
+}
+
+type DatedVehicleJourney struct {
+	DatedVehicleJourneyCode *string                  `xml:"DatedVehicleJourneyCode"`
+	FramedVehicleJourneyRef *FramedVehicleJourneyRef `xml:"FramedVehicleJourneyRef"`
+	DataFrameRef            *string                  `xml:"DataFrameRef"`
+	LineRef                 *string                  `xml:"LineRef"`
+	DirectionRef            *string                  `xml:"DirectionRef"`
+	VehicleMode             *string                  `xml:"VehicleMode"`
+	OperatorRef             *string                  `xml:"OperatorRef"`
+	DestinationDisplay      *string                  `xml:"DestinationDisplay"`
+	ExtraJourney            *bool                    `xml:"ExtraJourney"`
+	Cancellation            *bool                    `xml:"Cancellation"`
+	DataSource              *string                  `xml:"DataSource"`
+	DatedCalls              []DatedCall              `xml:"DatedCalls>DatedCall"`
+}
+
+type DatedCall struct {
+	StopPointRef       *string `xml:"StopPointRef"`
+	Order              *int32  `xml:"Order"`
+	DestinationDisplay *string `xml:"DestinationDisplay"`
+	AimedArrivalTime   *string `xml:"AimedArrivalTime"`
+	AimedDepartureTime *string `xml:"AimedDepartureTime"`
 }
 
 // Estimated Timetable (ET)
@@ -79,6 +141,9 @@ type EstimatedVehicleJourney struct {
 	VehicleRef               *string                  `xml:"VehicleRef"`
 	OriginAimedDepartureTime *string                  `xml:"OriginAimedDepartureTime"`
 	DataSource               *string                  `xml:"DataSource"`
+	DirectionRef             *string                  `xml:"DirectionRef"`
+	ExtraJourney             *bool                    `xml:"ExtraJourney"`
+	Cancellation             *bool                    `xml:"Cancellation"`
 	RecordedCalls            []RecordedCall           `xml:"RecordedCalls>RecordedCall"`
 	EstimatedCalls           []EstimatedCall          `xml:"EstimatedCalls>EstimatedCall"`
 }
@@ -106,6 +171,7 @@ type EstimatedCall struct {
 	ExpectedArrivalTime   *string `xml:"ExpectedArrivalTime"`
 	AimedDepartureTime    *string `xml:"AimedDepartureTime"`
 	ExpectedDepartureTime *string `xml:"ExpectedDepartureTime"`
+	Cancellation          *bool   `xml:"Cancellation"`
 }
 
 // Situation Exchange (SX)
@@ -115,19 +181,75 @@ type SituationExchangeDelivery struct {
 }
 
 type PtSituationElement struct {
+	CreationTime      *string            `xml:"CreationTime"`
 	ParticipantRef    *string            `xml:"ParticipantRef"`
 	SituationNumber   *string            `xml:"SituationNumber"`
+	Version           *string            `xml:"Version"`
+	VersionedAtTime   *string            `xml:"VersionedAtTime"`
+	Progress          *string            `xml:"Progress"` // open | published | closing | closed
 	PublicationWindow *PublicationWindow `xml:"PublicationWindow"`
 	Severity          *string            `xml:"Severity"`
 	Cause             *string            `xml:"Cause"`
 	Effect            *string            `xml:"Effect"`
 	ValidityPeriods   []ValidityPeriod   `xml:"ValidityPeriod"`
+	ReasonNames       []TranslatedText   `xml:"ReasonName"`
 	Summaries         []TranslatedText   `xml:"Summary"`
 	Descriptions      []TranslatedText   `xml:"Description"`
+	Details           []TranslatedText   `xml:"Detail"`
+	Advices           []TranslatedText   `xml:"Advice"`
+	Images            []Image            `xml:"Images>Image"`
 	Affects           *Affects           `xml:"Affects"`
+	Consequences      []Consequence      `xml:"Consequences>Consequence"`
+	PublishingActions []PublishingAction `xml:"PublishingActions>PublishingAction"`
 	InfoLinks         []InfoLink         `xml:"InfoLinks>InfoLink"`
 }
 
+// PublishingAction (SIRI 2.x / Nordic profile) states where and with which
+// texts a situation should be shown.
+type PublishingAction struct {
+	PublishAtScope              *PublishAtScope              `xml:"PublishAtScope"`
+	PassengerInformationActions []PassengerInformationAction `xml:"PassengerInformationAction"`
+}
+
+type PublishAtScope struct {
+	ScopeType *string  `xml:"ScopeType"` // general | operator | network | line | stopPlace | stopPoint | vehicleJourney ...
+	Affects   *Affects `xml:"Affects"`
+}
+
+type PassengerInformationAction struct {
+	ActionRef       *string          `xml:"ActionRef"`
+	RecordedAtTime  *string          `xml:"RecordedAtTime"`
+	Perspectives    []string         `xml:"Perspective"` // general | stopPoint | vehicleJourney
+	TextualContents []TextualContent `xml:"TextualContent"`
+}
+
+type TextualContent struct {
+	SummaryTexts        []TranslatedText `xml:"SummaryContent>SummaryText"`
+	ReasonTexts         []TranslatedText `xml:"ReasonContent>ReasonText"`
+	DescriptionTexts    []TranslatedText `xml:"DescriptionContent>DescriptionText"`
+	ConsequenceTexts    []TranslatedText `xml:"ConsequenceContent>ConsequenceText"`
+	RecommendationTexts []TranslatedText `xml:"RecommendationContent>RecommendationText"`
+	DurationTexts       []TranslatedText `xml:"DurationContent>DurationText"`
+	Remarks             []TranslatedText `xml:"RemarkContent>Remark"`
+}
+
+type Image struct {
+	ImageRef     *string `xml:"ImageRef"`
+	ImageContent *string `xml:"ImageContent"` // map | graphic | logo | ...
+}
+
+type Consequence struct {
+	Conditions []string           `xml:"Condition"`
+	Severity   *string            `xml:"Severity"`
+	Affects    *Affects           `xml:"Affects"`
+	Advice     *ConsequenceAdvice `xml:"Advice"`
+}
+
+type ConsequenceAdvice struct {
+	AdviceRef *string          `xml:"AdviceRef"`
+	Details   []TranslatedText `xml:"Details"`
+}
+
 type ValidityPeriod struct {
 	StartTime *string `xml:"StartTime"`
 	EndTime   *string `xml:"EndTime"`
@@ -143,12 +265,17 @@ type TranslatedText struct {
 	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
 }
 
+// InfoLink accepts both the bare form <InfoLink>uri</InfoLink> and the
+// structured form with <Uri> and <Label> children.
 type InfoLink struct {
-	Uri  string `xml:",chardata"`
-	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Uri    string           `xml:",chardata"`
+	Lang   string           `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Href   string           `xml:"Uri"`
+	Labels []TranslatedText `xml:"Label"`
 }
 
 type Affects struct {
+	Operators       []AffectedOperator       `xml:"Operators>AffectedOperator"`
 	StopPoints      []AffectedStopPoint      `xml:"StopPoints>AffectedStopPoint"`
 	VehicleJourneys []AffectedVehicleJourney `xml:"VehicleJourneys>AffectedVehicleJourney"`
 	Networks        []AffectedNetwork        `xml:"Networks>AffectedNetwork"`
@@ -161,6 +288,7 @@ type AffectedStopPoint struct {
 
 type AffectedVehicleJourney struct {
 	LineRef                  *string                  `xml:"LineRef"`
+	DirectionRef             *string                  `xml:"DirectionRef"`
 	VehicleJourneyRefs       []string                 `xml:"VehicleJourneyRef"`
 	DatedVehicleJourneyRefs  []string                 `xml:"DatedVehicleJourneyRef"`
 	FramedVehicleJourneyRef  *FramedVehicleJourneyRef `xml:"FramedVehicleJourneyRef"`
@@ -169,22 +297,149 @@ type AffectedVehicleJourney struct {
 }
 
 type AffectedRoute struct {
-	StopPoints StopPoints `xml:"StopPoints"`
+	RouteRef   *string             `xml:"RouteRef"`
+	Directions []AffectedDirection `xml:"Direction"`
+	Sections   []AffectedSection   `xml:"Sections>AffectedSection"`
+	StopPoints StopPoints          `xml:"StopPoints"`
+}
+
+type AffectedDirection struct {
+	DirectionRef  *string `xml:"DirectionRef"`
+	DirectionName *string `xml:"DirectionName"`
+}
+
+type AffectedSection struct {
+	SectionRef         *string             `xml:"SectionRef"`
+	IndirectSectionRef *IndirectSectionRef `xml:"IndirectSectionRef"`
+}
+
+type IndirectSectionRef struct {
+	FirstQuayRef *string `xml:"FirstQuayRef"`
+	LastQuayRef  *string `xml:"LastQuayRef"`
 }
 
 type StopPoints struct {
 	StopPoints []AffectedStopPoint `xml:"AffectedStopPoint"`
 }
 
+type AffectedOperator struct {
+	OperatorRef  *string `xml:"OperatorRef"`
+	OperatorName *string `xml:"OperatorName"`
+}
+
 type AffectedNetwork struct {
-	AffectedLines []AffectedLine `xml:"AffectedLine"`
+	AffectedOperators []AffectedOperator `xml:"AffectedOperator"`
+	NetworkRef        *string            `xml:"NetworkRef"`
+	VehicleMode       *string            `xml:"VehicleMode"`
+	AllLines          *string            `xml:"AllLines"` // present (empty) when every line is affected
+	AffectedLines     []AffectedLine     `xml:"AffectedLine"`
 }
 
 type AffectedLine struct {
-	LineRef *string         `xml:"LineRef"`
-	Routes  []AffectedRoute `xml:"Routes>AffectedRoute"`
+	LineRef    *string             `xml:"LineRef"`
+	Directions []AffectedDirection `xml:"Direction"`
+	Sections   []AffectedSection   `xml:"Sections>AffectedSection"`
+	Routes     []AffectedRoute     `xml:"Routes>AffectedRoute"`
 }
 
 type AffectedStopPlace struct {
-	StopPlaceRef *string `xml:"StopPlaceRef"`
+	StopPlaceRef            *string                  `xml:"StopPlaceRef"`
+	PlaceName               *string                  `xml:"PlaceName"`
+	AccessibilityAssessment *AccessibilityAssessment `xml:"AccessibilityAssessment"`
+	AffectedComponents      []AffectedComponent      `xml:"AffectedComponents>AffectedComponent"`
+}
+
+type AffectedComponent struct {
+	ComponentRef      *string `xml:"ComponentRef"`
+	ComponentType     *string `xml:"ComponentType"`     // entrance | quay | accessSpace | ...
+	AccessFeatureType *string `xml:"AccessFeatureType"` // lift | escalator | ramp | stairs | ...
+}
+
+type AccessibilityAssessment struct {
+	MobilityImpairedAccess *bool                     `xml:"MobilityImpairedAccess"`
+	Limitations            []AccessibilityLimitation `xml:"Limitations>AccessibilityLimitation"`
+}
+
+// AccessibilityLimitation values are "true", "false" or "unknown".
+type AccessibilityLimitation struct {
+	WheelchairAccess        *string `xml:"WheelchairAccess"`
+	StepFreeAccess          *string `xml:"StepFreeAccess"`
+	EscalatorFreeAccess     *string `xml:"EscalatorFreeAccess"`
+	LiftFreeAccess          *string `xml:"LiftFreeAccess"`
+	AudibleSignalsAvailable *string `xml:"AudibleSignalsAvailable"`
+	VisualSignsAvailable    *string `xml:"VisualSignsAvailable"`
+}
+
+// Facility Monitoring (FM)
+
+type FacilityMonitoringDelivery struct {
+	FacilityConditions []FacilityCondition `xml:"FacilityCondition"`
+}
+
+// FacilityCondition reports the status of a facility such as a lift or an
+// escalator. Producers send either a FacilityRef or a full Facility.
+type FacilityCondition struct {
+	FacilityRef    *string         `xml:"FacilityRef"`
+	Facility       *Facility       `xml:"Facility"`
+	FacilityStatus *FacilityStatus `xml:"FacilityStatus"`
+	ValidityPeriod *ValidityPeriod `xml:"ValidityPeriod"`
+}
+
+type Facility struct {
+	FacilityCode     *string           `xml:"FacilityCode"`
+	Descriptions     []TranslatedText  `xml:"Description"`
+	FacilityClass    *string           `xml:"FacilityClass"` // lift | escalator | travelator | ramp ...
+	FacilityLocation *FacilityLocation `xml:"FacilityLocation"`
+}
+
+type FacilityLocation struct {
+	StopPointRef          *string `xml:"StopPointRef"`
+	StopPlaceRef          *string `xml:"StopPlaceRef"`
+	StopPlaceComponentRef *string `xml:"StopPlaceComponentRef"`
+}
+
+type FacilityStatus struct {
+	Status                  *string                  `xml:"Status"` // available | notAvailable | partiallyAvailable | unknown
+	Descriptions            []TranslatedText         `xml:"Description"`
+	AccessibilityAssessment *AccessibilityAssessment `xml:"AccessibilityAssessment"`
+}
+
+// Connection Monitoring (CM)
+
+type ConnectionMonitoringFeederDelivery struct {
+	MonitoredFeederArrivals             []MonitoredFeederArrival             `xml:"MonitoredFeederArrival"`
+	MonitoredFeederArrivalCancellations []MonitoredFeederArrivalCancellation `xml:"MonitoredFeederArrivalCancellation"`
+}
+
+// MonitoredFeederArrival is the expected arrival of a feeder journey at an
+// interchange.
+type MonitoredFeederArrival struct {
+	RecordedAtTime      *string        `xml:"RecordedAtTime"`
+	InterchangeRef      *string        `xml:"InterchangeRef"`
+	ConnectionLinkRef   *string        `xml:"ConnectionLinkRef"`
+	StopPointRef        *string        `xml:"StopPointRef"`
+	FeederJourney       *FeederJourney `xml:"FeederJourney"`
+	VehicleAtStop       *bool          `xml:"VehicleAtStop"`
+	AimedArrivalTime    *string        `xml:"AimedArrivalTime"`
+	ExpectedArrivalTime *string        `xml:"ExpectedArrivalTime"`
+}
+
+type FeederJourney struct {
+	LineRef                 *string                  `xml:"LineRef"`
+	DirectionRef            *string                  `xml:"DirectionRef"`
+	FramedVehicleJourneyRef *FramedVehicleJourneyRef `xml:"FramedVehicleJourneyRef"`
+	PublishedLineName       *string                  `xml:"PublishedLineName"`
+}
+
+// MonitoredFeederArrivalCancellation withdraws a feeder arrival: the
+// connection is no longer guaranteed.
+type MonitoredFeederArrivalCancellation struct {
+	RecordedAtTime    *string                  `xml:"RecordedAtTime"`
+	InterchangeRef    *string                  `xml:"InterchangeRef"`
+	ConnectionLinkRef *string                  `xml:"ConnectionLinkRef"`
+	StopPointRef      *string                  `xml:"StopPointRef"`
+	LineRef           *string                  `xml:"LineRef"`
+	DirectionRef      *string                  `xml:"DirectionRef"`
+	VehicleJourneyRef *FramedVehicleJourneyRef `xml:"VehicleJourneyRef"`
+	Reasons           []TranslatedText         `xml:"Reason"`
 }
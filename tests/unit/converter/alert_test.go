@@ -0,0 +1,525 @@
+package converter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/formatter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// decodeSituation wraps a PtSituationElement body in an SX delivery and decodes it.
+func decodeSituation(t *testing.T, body string) *siri.PtSituationElement {
+	t.Helper()
+	doc := `<Siri version="2.0" xmlns="http://www.siri.org.uk/siri"><ServiceDelivery><SituationExchangeDelivery><Situations>` +
+		`<PtSituationElement>` + body + `</PtSituationElement>` +
+		`</Situations></SituationExchangeDelivery></ServiceDelivery></Siri>`
+	sd, err := formatter.DecodeSIRI(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(sd.SituationExchangeDeliveries) != 1 || len(sd.SituationExchangeDeliveries[0].Situations) != 1 {
+		t.Fatal("expected one situation")
+	}
+	return &sd.SituationExchangeDeliveries[0].Situations[0]
+}
+
+func stopIDs(sel []*gtfs.EntitySelector) []string {
+	var out []string
+	for _, s := range sel {
+		if s.StopId != nil {
+			out = append(out, *s.StopId)
+		}
+	}
+	return out
+}
+
+func TestMapSXToAlert_StopPlaces(t *testing.T) {
+	sx := decodeSituation(t, `
+		<SituationNumber>SX-1</SituationNumber>
+		<Summary xml:lang="en">Elevator out of order</Summary>
+		<Affects>
+			<StopPlaces>
+				<AffectedStopPlace>
+					<StopPlaceRef>SOFIA:StopPlace:ST1</StopPlaceRef>
+					<AccessibilityAssessment>
+						<MobilityImpairedAccess>false</MobilityImpairedAccess>
+					</AccessibilityAssessment>
+					<AffectedComponents>
+						<AffectedComponent>
+							<ComponentRef>ENT1</ComponentRef>
+							<ComponentType>entrance</ComponentType>
+						</AffectedComponent>
+						<AffectedComponent>
+							<ComponentRef>LIFT1</ComponentRef>
+							<AccessFeatureType>lift</AccessFeatureType>
+						</AffectedComponent>
+					</AffectedComponents>
+				</AffectedStopPlace>
+				<AffectedStopPlace>
+					<StopPlaceRef>SOFIA:StopPlace:Q3</StopPlaceRef>
+					<AffectedComponents>
+						<AffectedComponent>
+							<ComponentRef>SOFIA:StopPlaceComponent:LIFT9</ComponentRef>
+							<ComponentType>accessFeature</ComponentType>
+							<AccessFeatureType>lift</AccessFeatureType>
+						</AffectedComponent>
+					</AffectedComponents>
+				</AffectedStopPlace>
+			</StopPlaces>
+		</Affects>`)
+
+	stops, err := converter.LoadStopIndex(strings.NewReader("stop_id,stop_name,location_type,parent_station\nST1,Central,1,\nQ1,Central A,0,ST1\nQ2,Central B,0,ST1\n" +
+		"ST2,North,1,\nQ3,North A,0,ST2\nLIFT9,North lift,3,ST2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := converter.DefaultOptions()
+	opts.Stops = stops
+
+	e := converter.MapSXToAlert(sx, opts)
+	if e == nil {
+		t.Fatal("expected alert")
+	}
+	alert := e.Message.Alert
+	got := strings.Join(stopIDs(alert.InformedEntity), ",")
+	// LIFT1 is unknown to the index; LIFT9 is a generic node of ST2.
+	if got != "ST1,Q1,Q2,ENT1,Q3,ST2,LIFT9" {
+		t.Errorf("unexpected informed stops: %s", got)
+	}
+	if alert.GetEffect() != gtfs.Alert_ACCESSIBILITY_ISSUE {
+		t.Errorf("expected ACCESSIBILITY_ISSUE effect, got %v", alert.Effect)
+	}
+}
+
+func TestMapSXToAlert_OperatorAndModeSelectors(t *testing.T) {
+	sx := decodeSituation(t, `
+		<SituationNumber>SX-2</SituationNumber>
+		<Summary xml:lang="en">Strike: No service</Summary>
+		<Affects>
+			<Operators>
+				<AffectedOperator><OperatorRef>SOFIA:Operator:CGM</OperatorRef></AffectedOperator>
+			</Operators>
+			<Networks>
+				<AffectedNetwork>
+					<AffectedOperator><OperatorRef>SOFIA:Operator:MET</OperatorRef></AffectedOperator>
+					<VehicleMode>metro</VehicleMode>
+					<AllLines/>
+				</AffectedNetwork>
+				<AffectedNetwork>
+					<VehicleMode>tram</VehicleMode>
+					<AllLines/>
+				</AffectedNetwork>
+				<AffectedNetwork>
+					<AllLines/>
+				</AffectedNetwork>
+			</Networks>
+		</Affects>`)
+
+	opts := converter.DefaultOptions()
+	opts.NetworkAgencyID = "SOFIA"
+	e := converter.MapSXToAlert(sx, opts)
+	if e == nil {
+		t.Fatal("expected alert")
+	}
+	ie := e.Message.Alert.InformedEntity
+	if len(ie) != 4 {
+		t.Fatalf("expected 4 selectors, got %d", len(ie))
+	}
+	if ie[0].AgencyId == nil || *ie[0].AgencyId != "CGM" || ie[0].RouteType != nil {
+		t.Errorf("expected agency-only selector CGM, got %+v", ie[0])
+	}
+	if ie[1].AgencyId == nil || *ie[1].AgencyId != "MET" || ie[1].RouteType == nil || *ie[1].RouteType != 1 {
+		t.Errorf("expected agency MET with route_type 1, got %+v", ie[1])
+	}
+	if ie[2].AgencyId != nil || ie[2].RouteType == nil || *ie[2].RouteType != 0 {
+		t.Errorf("expected route_type 0 selector, got %+v", ie[2])
+	}
+	if ie[3].AgencyId == nil || *ie[3].AgencyId != "SOFIA" {
+		t.Errorf("expected network-wide agency selector, got %+v", ie[3])
+	}
+
+	view := gtfsrt.FromProtoEntity(e.Message).Alert.InformedEntity[1]
+	if view.AgencyId == nil || *view.AgencyId != "MET" || view.RouteType == nil || *view.RouteType != 1 {
+		t.Errorf("agency_id/route_type not carried into the JSON view: %+v", view)
+	}
+}
+
+func TestMapSXToAlert_CombinedRouteStopDirection(t *testing.T) {
+	sx := decodeSituation(t, `
+		<SituationNumber>SX-3</SituationNumber>
+		<Affects>
+			<Networks>
+				<AffectedNetwork>
+					<AffectedLine>
+						<LineRef>SOFIA:Line:94</LineRef>
+						<Direction><DirectionRef>inbound</DirectionRef></Direction>
+						<Routes>
+							<AffectedRoute>
+								<RouteRef>R94-IN</RouteRef>
+								<StopPoints>
+									<AffectedStopPoint><StopPointRef>SOFIA:Quay:100</StopPointRef></AffectedStopPoint>
+									<AffectedStopPoint><StopPointRef>SOFIA:Quay:101</StopPointRef></AffectedStopPoint>
+									<AffectedStopPoint><StopPointRef>SOFIA:Quay:100</StopPointRef></AffectedStopPoint>
+								</StopPoints>
+							</AffectedRoute>
+						</Routes>
+					</AffectedLine>
+				</AffectedNetwork>
+			</Networks>
+			<VehicleJourneys>
+				<AffectedVehicleJourney>
+					<LineRef>SOFIA:Line:94</LineRef>
+					<DirectionRef>outbound</DirectionRef>
+					<Routes>
+						<AffectedRoute>
+							<Sections>
+								<AffectedSection>
+									<IndirectSectionRef>
+										<FirstQuayRef>SOFIA:Quay:200</FirstQuayRef>
+										<LastQuayRef>SOFIA:Quay:205</LastQuayRef>
+									</IndirectSectionRef>
+								</AffectedSection>
+							</Sections>
+						</AffectedRoute>
+					</Routes>
+				</AffectedVehicleJourney>
+			</VehicleJourneys>
+		</Affects>`)
+
+	e := converter.MapSXToAlert(sx, converter.DefaultOptions())
+	if e == nil {
+		t.Fatal("expected alert")
+	}
+	var got []string
+	for _, ie := range e.Message.Alert.InformedEntity {
+		if ie.RouteId == nil || ie.DirectionId == nil || ie.StopId == nil {
+			t.Fatalf("expected combined route+direction+stop selector, got %+v", ie)
+		}
+		got = append(got, *ie.RouteId+"/"+string(rune('0'+*ie.DirectionId))+"/"+*ie.StopId)
+	}
+	want := "94/0/200,94/0/205,94/1/100,94/1/101"
+	if strings.Join(got, ",") != want {
+		t.Errorf("expected %s, got %s", want, strings.Join(got, ","))
+	}
+}
+
+func TestMapSXToAlert_ProgressAndVersioning(t *testing.T) {
+	v2 := decodeSituation(t, `<SituationNumber>SX-4</SituationNumber><Version>2</Version><Progress>open</Progress>`)
+	v1 := decodeSituation(t, `<SituationNumber>SX-4</SituationNumber><Version>1</Version><Progress>open</Progress>`)
+	closed := decodeSituation(t, `<SituationNumber>SX-4</SituationNumber><Version>3</Version><Progress>closed</Progress>
+		<Affects><StopPoints><AffectedStopPoint><StopPointRef>SOFIA:Quay:Q1</StopPointRef></AffectedStopPoint></StopPoints></Affects>`)
+
+	var reasons []string
+	opts := converter.DefaultOptions()
+	opts.SituationVersions = converter.NewSituationVersions()
+	opts.SXClosedAsDeleted = true
+	opts.OnReject = func(r converter.Rejection) { reasons = append(reasons, r.Reason) }
+
+	if e := converter.MapSXToAlert(v2, opts); e == nil || e.Message.Alert == nil {
+		t.Fatal("expected version 2 to be emitted")
+	}
+	if e := converter.MapSXToAlert(v1, opts); e != nil {
+		t.Error("expected older version 1 to be rejected")
+	}
+	e := converter.MapSXToAlert(closed, opts)
+	if e == nil || !e.Deleted || !e.Message.GetIsDeleted() {
+		t.Fatal("expected closed situation to be emitted as a deleted entity")
+	}
+	if got := strings.Join(stopIDs(e.Message.Alert.InformedEntity), ","); got != "Q1" {
+		t.Errorf("tombstone must keep the informed entities, got %q", got)
+	}
+	if msg := converter.BuildFeedMessage([]converter.Entity{*e}); len(msg.Entity) != 0 {
+		t.Error("tombstones must not appear in a FULL_DATASET feed")
+	}
+	if e := converter.MapSXToAlert(v2, opts); e != nil {
+		t.Error("expected closed situation to stay closed")
+	}
+	if strings.Join(reasons, ",") != converter.RejectOutdatedVersion+","+converter.RejectOutdatedVersion {
+		t.Errorf("unexpected rejections: %v", reasons)
+	}
+
+	if view := gtfsrt.FromProtoEntity(e.Message); view.IsDeleted == nil || !*view.IsDeleted {
+		t.Error("is_deleted not carried into the JSON view")
+	}
+}
+
+func TestConvertSIRI_PrunesSituationVersions(t *testing.T) {
+	now := time.Date(2025, 9, 12, 10, 0, 0, 0, time.UTC)
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return now }
+	opts.SituationVersions = converter.NewSituationVersions()
+
+	converter.MapSXToAlert(decodeSituation(t, `<SituationNumber>SX-5</SituationNumber><Version>1</Version>`), opts)
+	if n := opts.SituationVersions.Len(); n != 1 {
+		t.Fatalf("expected 1 tracked situation, got %d", n)
+	}
+
+	now = now.Add(converter.DefaultSituationRetention + time.Minute)
+	if _, err := converter.ConvertSIRI(&siri.ServiceDelivery{}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := opts.SituationVersions.Len(); n != 0 {
+		t.Errorf("expected situation to be forgotten, got %d", n)
+	}
+}
+
+func TestConvertSIRI_NewestSituationVersionWins(t *testing.T) {
+	sd := &siri.ServiceDelivery{SituationExchangeDeliveries: []siri.SituationExchangeDelivery{{
+		Situations: []siri.PtSituationElement{
+			*decodeSituation(t, `<SituationNumber>SX-5</SituationNumber><Version>1</Version><Summary xml:lang="en">old</Summary>`),
+			*decodeSituation(t, `<SituationNumber>SX-5</SituationNumber><Version>2</Version><Summary xml:lang="en">new</Summary>`),
+			*decodeSituation(t, `<SituationNumber>SX-5</SituationNumber><Version>1</Version><Summary xml:lang="en">old</Summary>`),
+		},
+	}}}
+	ents, err := converter.ConvertSIRI(sd, converter.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(ents))
+	}
+	if got := ents[0].Message.Alert.HeaderText.Translation[1].GetText(); got != "new" {
+		t.Errorf("expected newest version, got %q", got)
+	}
+}
+
+func TestMapSXToAlert_PublicationFiltering(t *testing.T) {
+	now, _ := siri.ParseISOTime("2025-09-12T10:00:00Z")
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return now }
+	opts.SXHideBeforePublication = true
+	opts.SXDropAfterPublication = true
+	opts.SXDefaultTTL = 48 * time.Hour
+
+	future := decodeSituation(t, `<SituationNumber>F</SituationNumber>
+		<PublicationWindow><StartTime>2025-09-13T00:00:00Z</StartTime></PublicationWindow>`)
+	if e := converter.MapSXToAlert(future, opts); e != nil {
+		t.Error("expected situation before publication start to be hidden")
+	}
+
+	ended := decodeSituation(t, `<SituationNumber>E</SituationNumber>
+		<ValidityPeriod><StartTime>2025-09-11T00:00:00Z</StartTime><EndTime>2025-09-11T12:00:00Z</EndTime></ValidityPeriod>`)
+	if e := converter.MapSXToAlert(ended, opts); e != nil {
+		t.Error("expected situation whose validity ended to be dropped")
+	}
+
+	open := decodeSituation(t, `<SituationNumber>O</SituationNumber>
+		<PublicationWindow><StartTime>2025-09-12T00:00:00Z</StartTime></PublicationWindow>
+		<ValidityPeriod><StartTime>2025-09-12T06:00:00Z</StartTime></ValidityPeriod>`)
+	e := converter.MapSXToAlert(open, opts)
+	if e == nil {
+		t.Fatal("expected open-ended situation to be published")
+	}
+	if e.TTL != 48*time.Hour {
+		t.Errorf("expected default TTL for open-ended situation, got %v", e.TTL)
+	}
+	if p := e.Message.Alert.ActivePeriod; len(p) != 1 || p[0].GetStart() != uint64(now.Add(-4*time.Hour).Unix()) {
+		t.Errorf("expected validity period as active period, got %+v", p)
+	}
+
+	// The publication window alone never becomes an active period.
+	published := decodeSituation(t, `<SituationNumber>P</SituationNumber>
+		<PublicationWindow><StartTime>2025-09-12T00:00:00Z</StartTime></PublicationWindow>`)
+	if e := converter.MapSXToAlert(published, opts); e == nil || len(e.Message.Alert.ActivePeriod) != 0 {
+		t.Errorf("expected no active period without validity periods")
+	}
+
+	// Without publication filters an ended situation is still emitted, but
+	// only for the grace period, not the open-ended default.
+	opts.SXDropAfterPublication = false
+	if e := converter.MapSXToAlert(ended, opts); e == nil || e.TTL != opts.VMGracePeriod {
+		t.Errorf("expected grace-period TTL for an ended situation, got %+v", e)
+	}
+}
+
+func TestMapSXToAlert_TextsAndImages(t *testing.T) {
+	sx := decodeSituation(t, `
+		<SituationNumber>SX-6</SituationNumber>
+		<ReasonName xml:lang="en">Track works</ReasonName>
+		<Summary xml:lang="en">Line 5 diverted</Summary>
+		<Description xml:lang="en">Trams run via Main St.</Description>
+		<Detail xml:lang="en">Stops 12-15 are not served.</Detail>
+		<Advice xml:lang="en">Allow 10 extra minutes.</Advice>
+		<Images><Image><ImageRef>https://example.org/map.png?v=2</ImageRef><ImageContent>map</ImageContent></Image></Images>
+		<Consequences>
+			<Consequence>
+				<Condition>diverted</Condition>
+				<Advice><Details xml:lang="en">Use bus 73 instead.</Details></Advice>
+			</Consequence>
+		</Consequences>
+		<InfoLinks>
+			<InfoLink><Uri>https://example.org/works</Uri><Label xml:lang="en">More information</Label></InfoLink>
+		</InfoLinks>`)
+
+	opts := converter.DefaultOptions()
+	opts.SXComposeDescription = true
+	opts.SXTextToSpeech = true
+	e := converter.MapSXToAlert(sx, opts)
+	if e == nil {
+		t.Fatal("expected alert")
+	}
+	a := e.Message.Alert
+
+	want := "Trams run via Main St.\n\nStops 12-15 are not served.\n\nAllow 10 extra minutes.\n\nUse bus 73 instead.\n\nMore information: https://example.org/works"
+	if got := a.DescriptionText.Translation[1].GetText(); got != want {
+		t.Errorf("unexpected description:\n%s", got)
+	}
+	if a.TtsHeaderText == nil || a.TtsHeaderText.Translation[1].GetText() != "Line 5 diverted" {
+		t.Error("expected tts_header_text from summary")
+	}
+	if a.Url.Translation[1].GetText() != "https://example.org/works" {
+		t.Errorf("expected url from structured InfoLink, got %q", a.Url.Translation[1].GetText())
+	}
+	if img := gtfsrt.AlertImage(a); img == nil || img.LocalizedImage[0].MediaType != "image/png" {
+		t.Errorf("unexpected image: %+v", img)
+	}
+	if cd := gtfsrt.AlertCauseDetail(a); cd == nil || cd.Translation[1].GetText() != "Track works" {
+		t.Error("expected cause_detail from ReasonName")
+	}
+	if ed := gtfsrt.AlertEffectDetail(a); ed == nil || ed.Translation[0].GetText() != "Променен маршрут" || ed.Translation[1].GetText() != "Diverted" {
+		t.Errorf("expected translated effect_detail from consequence condition, got %v", ed)
+	}
+	if gtfsrt.AlertImageAlternativeText(a) != nil {
+		t.Error("image_alternative_text must not be invented from the header")
+	}
+
+	b, err := gtfsrt.MarshalPBF(converter.BuildFeedMessage([]converter.Entity{*e}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"https://example.org/map.png?v=2", "Track works", "Diverted"} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("expected %q in PBF output", s)
+		}
+	}
+}
+
+func TestMapSXToAlert_PublishingActions(t *testing.T) {
+	sx := decodeSituation(t, `
+		<SituationNumber>SX-PA</SituationNumber>
+		<Summary xml:lang="en">Works on line 1</Summary>
+		<Affects>
+			<Networks><AffectedNetwork><AffectedLine><LineRef>SOFIA:Line:1</LineRef></AffectedLine></AffectedNetwork></Networks>
+		</Affects>
+		<PublishingActions>
+			<PublishingAction>
+				<PublishAtScope>
+					<ScopeType>stopPoint</ScopeType>
+					<Affects>
+						<StopPoints><AffectedStopPoint><StopPointRef>SOFIA:Quay:Q9</StopPointRef></AffectedStopPoint></StopPoints>
+					</Affects>
+				</PublishAtScope>
+				<PassengerInformationAction>
+					<ActionRef>A1</ActionRef>
+					<Perspective>general</Perspective>
+					<TextualContent>
+						<SummaryContent><SummaryText xml:lang="en">Stop Q9 closed</SummaryText></SummaryContent>
+						<DescriptionContent><DescriptionText xml:lang="en">Track works.</DescriptionText></DescriptionContent>
+						<RecommendationContent><RecommendationText xml:lang="en">Use stop Q10.</RecommendationText></RecommendationContent>
+					</TextualContent>
+				</PassengerInformationAction>
+			</PublishingAction>
+		</PublishingActions>`)
+
+	opts := converter.DefaultOptions()
+	e := converter.MapSXToAlert(sx, opts)
+	if e == nil {
+		t.Fatal("expected alert")
+	}
+	if got := stopIDs(e.Message.Alert.InformedEntity); len(got) != 0 {
+		t.Errorf("scope used without SXUsePublishAtScope: %v", got)
+	}
+
+	opts.SXUsePublishAtScope = true
+	opts.SXUsePassengerInformation = true
+	alert := converter.MapSXToAlert(sx, opts).Message.Alert
+	if got := strings.Join(stopIDs(alert.InformedEntity), ","); got != "Q9" {
+		t.Errorf("unexpected informed stops: %s", got)
+	}
+	if got := enText(alert.HeaderText); got != "Stop Q9 closed" {
+		t.Errorf("header = %q", got)
+	}
+	if got := enText(alert.DescriptionText); got != "Track works.\n\nUse stop Q10." {
+		t.Errorf("description = %q", got)
+	}
+}
+
+func enText(ts *gtfs.TranslatedString) string {
+	for _, tr := range ts.GetTranslation() {
+		if tr.GetLanguage() == "en" {
+			return tr.GetText()
+		}
+	}
+	return ""
+}
+
+func TestMapSXToAlert_DataFrameRefDates(t *testing.T) {
+	cases := []struct {
+		name, journey, want string
+	}{
+		{"iso", `<FramedVehicleJourneyRef><DataFrameRef>2024-01-01</DataFrameRef><DatedVehicleJourneyRef>T1</DatedVehicleJourneyRef></FramedVehicleJourneyRef>`, "20240101"},
+		{"compact", `<FramedVehicleJourneyRef><DataFrameRef>20240102</DataFrameRef><DatedVehicleJourneyRef>T1</DatedVehicleJourneyRef></FramedVehicleJourneyRef>`, "20240102"},
+		{"prefixed", `<FramedVehicleJourneyRef><DataFrameRef>SOFIA:DataFrame:2024-01-03</DataFrameRef><DatedVehicleJourneyRef>T1</DatedVehicleJourneyRef></FramedVehicleJourneyRef>`, "20240103"},
+		{"origin fallback", `<FramedVehicleJourneyRef><DataFrameRef>2024-13-40</DataFrameRef><DatedVehicleJourneyRef>T1</DatedVehicleJourneyRef></FramedVehicleJourneyRef><OriginAimedDepartureTime>2024-01-04T08:00:00+02:00</OriginAimedDepartureTime>`, "20240104"},
+		{"validity fallback", `<FramedVehicleJourneyRef><DataFrameRef>current</DataFrameRef><DatedVehicleJourneyRef>T1</DatedVehicleJourneyRef></FramedVehicleJourneyRef>`, "20240105"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sx := decodeSituation(t, `
+				<SituationNumber>SX-DF</SituationNumber>
+				<ValidityPeriod><StartTime>2024-01-05T06:00:00+02:00</StartTime></ValidityPeriod>
+				<Summary xml:lang="en">Trip cancelled</Summary>
+				<Affects><VehicleJourneys><AffectedVehicleJourney>`+tc.journey+`</AffectedVehicleJourney></VehicleJourneys></Affects>`)
+			opts := converter.DefaultOptions()
+			e := converter.MapSXToAlert(sx, opts)
+			if e == nil {
+				t.Fatal("expected alert")
+			}
+			ie := e.Message.Alert.InformedEntity
+			if len(ie) != 1 || ie[0].Trip == nil {
+				t.Fatalf("expected one trip selector, got %+v", ie)
+			}
+			if got := ie[0].Trip.GetStartDate(); got != tc.want {
+				t.Errorf("start_date = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMapSXToAlert_TripSelectorsKeepRoute(t *testing.T) {
+	cases := []struct {
+		name, journey, want string
+	}{
+		{"line", `<LineRef>SOFIA:Line:94</LineRef><DatedVehicleJourneyRef>T1</DatedVehicleJourneyRef>`, "94"},
+		{"no route from RouteRef", `<DatedVehicleJourneyRef>T1</DatedVehicleJourneyRef><Routes><AffectedRoute><RouteRef>SOFIA:Route:88</RouteRef></AffectedRoute></Routes>`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sx := decodeSituation(t, `
+				<SituationNumber>SX-RT</SituationNumber>
+				<Summary xml:lang="en">Trip diverted</Summary>
+				<Affects><VehicleJourneys><AffectedVehicleJourney>`+tc.journey+`</AffectedVehicleJourney></VehicleJourneys></Affects>`)
+			e := converter.MapSXToAlert(sx, converter.DefaultOptions())
+			if e == nil {
+				t.Fatal("expected alert")
+			}
+			ie := e.Message.Alert.InformedEntity
+			if len(ie) != 1 || ie[0].Trip.GetTripId() != "T1" {
+				t.Fatalf("expected one trip selector, got %+v", ie)
+			}
+			var got string
+			if ie[0].RouteId != nil {
+				got = *ie[0].RouteId
+			}
+			if got != tc.want {
+				t.Errorf("route_id = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
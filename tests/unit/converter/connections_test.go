@@ -0,0 +1,100 @@
+package converter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/formatter"
+)
+
+func TestConvertSIRI_ConnectionMonitoring(t *testing.T) {
+	doc := `<Siri version="2.0" xmlns="http://www.siri.org.uk/siri"><ServiceDelivery>
+		<EstimatedTimetableDelivery><EstimatedJourneyVersionFrame><EstimatedVehicleJourney>
+			<FramedVehicleJourneyRef><DataFrameRef>2025-09-12</DataFrameRef><DatedVehicleJourneyRef>D1</DatedVehicleJourneyRef></FramedVehicleJourneyRef>
+			<OriginAimedDepartureTime>2025-09-12T09:00:00Z</OriginAimedDepartureTime>
+			<EstimatedCalls><EstimatedCall>
+				<StopPointRef>SOFIA:Quay:HUB</StopPointRef><Order>3</Order>
+				<AimedArrivalTime>2025-09-12T09:29:00Z</AimedArrivalTime><ExpectedArrivalTime>2025-09-12T09:29:00Z</ExpectedArrivalTime>
+				<AimedDepartureTime>2025-09-12T09:30:00Z</AimedDepartureTime><ExpectedDepartureTime>2025-09-12T09:30:00Z</ExpectedDepartureTime>
+			</EstimatedCall><EstimatedCall>
+				<StopPointRef>SOFIA:Quay:NEXT</StopPointRef><Order>4</Order>
+				<AimedArrivalTime>2025-09-12T09:35:00Z</AimedArrivalTime><ExpectedArrivalTime>2025-09-12T09:35:00Z</ExpectedArrivalTime>
+			</EstimatedCall></EstimatedCalls>
+		</EstimatedVehicleJourney><EstimatedVehicleJourney>
+			<FramedVehicleJourneyRef><DataFrameRef>2025-09-13</DataFrameRef><DatedVehicleJourneyRef>D1</DatedVehicleJourneyRef></FramedVehicleJourneyRef>
+			<OriginAimedDepartureTime>2025-09-13T09:00:00Z</OriginAimedDepartureTime>
+			<EstimatedCalls><EstimatedCall>
+				<StopPointRef>SOFIA:Quay:HUB</StopPointRef><Order>3</Order>
+				<AimedDepartureTime>2025-09-13T09:30:00Z</AimedDepartureTime><ExpectedDepartureTime>2025-09-13T09:30:00Z</ExpectedDepartureTime>
+			</EstimatedCall></EstimatedCalls>
+		</EstimatedVehicleJourney></EstimatedJourneyVersionFrame></EstimatedTimetableDelivery>
+		<ConnectionMonitoringFeederDelivery>
+			<MonitoredFeederArrival>
+				<InterchangeRef>IC1</InterchangeRef>
+				<StopPointRef>SOFIA:Quay:HUB</StopPointRef>
+				<FeederJourney>
+					<LineRef>SOFIA:Line:11</LineRef>
+					<!-- the feeder belongs to the previous service day -->
+					<FramedVehicleJourneyRef><DataFrameRef>2025-09-11</DataFrameRef><DatedVehicleJourneyRef>F1</DatedVehicleJourneyRef></FramedVehicleJourneyRef>
+				</FeederJourney>
+				<AimedArrivalTime>2025-09-12T09:25:00Z</AimedArrivalTime>
+				<ExpectedArrivalTime>2025-09-12T09:31:00Z</ExpectedArrivalTime>
+			</MonitoredFeederArrival>
+			<MonitoredFeederArrival>
+				<InterchangeRef>IC2</InterchangeRef>
+				<StopPointRef>SOFIA:Quay:HUB</StopPointRef>
+				<ExpectedArrivalTime>2025-09-12T09:40:00Z</ExpectedArrivalTime>
+			</MonitoredFeederArrival>
+			<MonitoredFeederArrivalCancellation>
+				<InterchangeRef>IC3</InterchangeRef>
+				<StopPointRef>SOFIA:Quay:HUB</StopPointRef>
+				<LineRef>SOFIA:Line:12</LineRef>
+				<Reason xml:lang="en">Feeder delayed too long</Reason>
+			</MonitoredFeederArrivalCancellation>
+		</ConnectionMonitoringFeederDelivery>
+	</ServiceDelivery></Siri>`
+	sd, err := formatter.DecodeSIRI(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 9, 20, 0, 0, time.UTC) }
+	opts.Interchanges = map[string]converter.Interchange{
+		"IC1": {DistributorTripID: "D1", MinTransfer: 2 * time.Minute, MaxWait: 2 * time.Minute},
+		"IC2": {DistributorTripID: "D2", StopID: "HUB2", MinTransfer: time.Minute},
+		"IC3": {DistributorTripID: "D3"},
+	}
+
+	ents, err := converter.ConvertSIRI(sd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// D2 has no predicted departure, so it is not held.
+	if got := strings.Join(entityIDs(ents), ","); got != "D1-20250912,D1-20250913,connection-IC3" {
+		t.Fatalf("unexpected entities: %s", got)
+	}
+
+	// Feeder arrives 09:31 + 2 min transfer, capped at a 2 min hold that
+	// carries over to the next stop.
+	stus := ents[0].Message.TripUpdate.StopTimeUpdate
+	if arr, dep := stus[0].Arrival.GetTime(), stus[0].Departure.GetTime(); arr != 1757669340 || dep != 1757669520 {
+		t.Errorf("distributor arrival/departure = %d/%d, want 09:29/09:32", arr, dep)
+	}
+	if arr := stus[1].Arrival.GetTime(); arr != 1757669820 {
+		t.Errorf("next stop arrival = %d, want 09:37", arr)
+	}
+	// The next day's trip is left alone.
+	if dep := ents[1].Message.TripUpdate.StopTimeUpdate[0].Departure.GetTime(); dep != 1757755800 {
+		t.Errorf("next day departure = %d, want unchanged", dep)
+	}
+
+	alert := ents[2].Message.Alert
+	if got := enText(alert.HeaderText); got != "Connection with line 12 not guaranteed" {
+		t.Errorf("header = %q", got)
+	}
+	if ie := alert.InformedEntity; len(ie) != 1 || ie[0].Trip.GetTripId() != "D3" || ie[0].GetStopId() != "HUB" {
+		t.Errorf("unexpected informed entity: %+v", ie)
+	}
+}
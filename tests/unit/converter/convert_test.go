@@ -0,0 +1,128 @@
+package converter_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+func journeyActivity(trip, vehicle, recorded string) siri.VehicleActivity {
+	va := vehicleActivity(vehicle, 42.7, 23.3)
+	va.RecordedAtTime = strPtr(recorded)
+	va.MonitoredVehicleJourney.FramedVehicleJourneyRef = &siri.FramedVehicleJourneyRef{
+		DataFrameRef:           strPtr("2025-09-12"),
+		DatedVehicleJourneyRef: strPtr(trip),
+	}
+	va.MonitoredVehicleJourney.OriginAimedDepartureTime = strPtr("2025-09-12T09:00:00Z")
+	return *va
+}
+
+func vmDelivery(activities ...siri.VehicleActivity) *siri.ServiceDelivery {
+	return &siri.ServiceDelivery{
+		VehicleMonitoringDeliveries: []siri.VehicleMonitoringDelivery{{VehicleActivities: activities}},
+	}
+}
+
+func entityIDs(ents []converter.Entity) []string {
+	var ids []string
+	for _, e := range ents {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestConvertSIRI_VehicleEntityIDStrategy(t *testing.T) {
+	// Two coupled train units on the same journey
+	sd := vmDelivery(
+		journeyActivity("T1", "U1", "2025-09-12T10:00:00Z"),
+		journeyActivity("T1", "U2", "2025-09-12T10:00:05Z"),
+	)
+
+	tests := []struct {
+		strategy converter.EntityIDStrategy
+		want     []string
+	}{
+		{converter.EntityIDTrip, []string{"T1-20250912"}},
+		{converter.EntityIDVehicle, []string{"U1", "U2"}},
+		{converter.EntityIDTripVehicle, []string{"T1-20250912-U1", "T1-20250912-U2"}},
+	}
+	for _, tt := range tests {
+		opts := converter.DefaultOptions()
+		opts.VMEntityID = tt.strategy
+		ents, err := converter.ConvertSIRI(sd, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := entityIDs(ents)
+		if len(got) != len(tt.want) {
+			t.Fatalf("strategy %d: expected %v, got %v", tt.strategy, tt.want, got)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("strategy %d: expected %v, got %v", tt.strategy, tt.want, got)
+			}
+		}
+	}
+
+	// With trip IDs the newest sample must win
+	ents, _ := converter.ConvertSIRI(sd, converter.DefaultOptions())
+	if v := ents[0].Message.Vehicle.Vehicle.GetId(); v != "U2" {
+		t.Errorf("expected newest vehicle U2 to win, got %s", v)
+	}
+}
+
+func TestConvertSIRI_OneEntityPerVehicle(t *testing.T) {
+	// Interlined bus reported on the finishing and the continuing journey
+	sd := vmDelivery(
+		journeyActivity("T1", "B7", "2025-09-12T10:00:00Z"),
+		journeyActivity("T2", "B7", "2025-09-12T10:00:30Z"),
+	)
+	opts := converter.DefaultOptions()
+	opts.VMEntityID = converter.EntityIDTripVehicle
+	opts.VMOneEntityPerVehicle = true
+	ents, _ := converter.ConvertSIRI(sd, opts)
+	if len(ents) != 1 || ents[0].ID != "T2-20250912-B7" {
+		t.Errorf("expected only the continuing journey, got %v", entityIDs(ents))
+	}
+}
+
+func TestConvertSIRI_NumericTimestamps(t *testing.T) {
+	ents, err := converter.ConvertSIRI(vmDelivery(journeyActivity("T1", "U1", "2025-09-12T10:00:00Z")), converter.DefaultOptions())
+	if err != nil || len(ents) != 1 {
+		t.Fatalf("unexpected result: %v %v", ents, err)
+	}
+	vp := ents[0].Message.Vehicle
+	if vp.Timestamp == nil || *vp.Timestamp != 1757671200 {
+		t.Fatalf("unexpected vehicle timestamp: %v", vp.Timestamp)
+	}
+
+	// The JSON view keeps the protobuf JSON form of 64-bit integers.
+	b, err := json.Marshal(gtfsrt.FromProtoEntity(ents[0].Message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"timestamp":"1757671200"`) {
+		t.Errorf("unexpected JSON: %s", b)
+	}
+
+	feed := gtfsrt.NewFeedMessage()
+	feed.Entity = append(feed.Entity, gtfsrt.FromProtoEntity(ents[0].Message))
+	pbf, err := gtfsrt.MarshalPBF(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, err := gtfsrt.UnmarshalPBFToProto(pbf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pm.GetEntity()[0].GetVehicle().GetTimestamp(); got != 1757671200 {
+		t.Errorf("PBF vehicle timestamp = %d", got)
+	}
+	if pm.GetHeader().GetTimestamp() != feed.Header.Timestamp {
+		t.Errorf("PBF header timestamp = %d, want %d", pm.GetHeader().GetTimestamp(), feed.Header.Timestamp)
+	}
+}
@@ -0,0 +1,201 @@
+package converter_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+func TestDifferential(t *testing.T) {
+	convert := func(activities ...string) []converter.Entity {
+		t.Helper()
+		sd := vmDelivery()
+		for i := 0; i+1 < len(activities); i += 2 {
+			sd.VehicleMonitoringDeliveries[0].VehicleActivities = append(sd.VehicleMonitoringDeliveries[0].VehicleActivities,
+				journeyActivity(activities[i], "U-"+activities[i], activities[i+1]))
+		}
+		ents, err := converter.ConvertSIRI(sd, converter.DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ents
+	}
+
+	d := converter.NewDifferential()
+	v1 := d.Update(convert("T1", "2025-09-12T10:00:00Z", "T2", "2025-09-12T10:00:00Z"))
+	// T1 unchanged, T2 moved on, T3 new.
+	v2 := d.Update(convert("T1", "2025-09-12T10:00:00Z", "T2", "2025-09-12T10:00:30Z", "T3", "2025-09-12T10:00:30Z"))
+	// T2 gone.
+	v3 := d.Update(convert("T1", "2025-09-12T10:00:00Z", "T3", "2025-09-12T10:00:30Z"))
+
+	if v1 != 1 || v2 != 2 || v3 != 3 || d.Version() != 3 || d.Len() != 3 {
+		t.Fatalf("unexpected versions %d %d %d (len %d)", v1, v2, v3, d.Len())
+	}
+
+	full, _ := d.Changes(0)
+	if got := entityIDs(full); !reflect.DeepEqual(got, []string{"T1-20250912", "T3-20250912"}) {
+		t.Errorf("full dataset = %v", got)
+	}
+
+	changes, version := d.Changes(v1)
+	if version != 3 || !reflect.DeepEqual(entityIDs(changes), []string{"T2-20250912", "T3-20250912"}) {
+		t.Fatalf("changes since v1 = %v (version %d)", entityIDs(changes), version)
+	}
+	del := changes[0].Message
+	if !del.GetIsDeleted() || del.Vehicle == nil || del.Vehicle.Position != nil || del.Vehicle.Trip.GetTripId() != "T2" {
+		t.Errorf("expected a T2 tombstone, got %+v", del)
+	}
+
+	if changes, _ := d.Changes(v3); len(changes) != 0 {
+		t.Errorf("expected no changes since the latest version, got %v", entityIDs(changes))
+	}
+
+	msg := converter.BuildDifferentialFeedMessage(changes)
+	if msg.Header.Incrementality == nil || *msg.Header.Incrementality != gtfsrt.IncrementalityDifferential || len(msg.Entity) != 2 {
+		t.Errorf("unexpected differential message: %+v", msg.Header)
+	}
+	if *gtfsrt.NewFeedMessageHeader().Incrementality != 0 {
+		t.Error("FULL_DATASET must be 0")
+	}
+}
+
+func TestDifferential_TombstoneRetention(t *testing.T) {
+	now := time.Date(2025, 9, 12, 10, 0, 0, 0, time.UTC)
+	d := converter.NewDifferential()
+	d.Retention = 5 * time.Minute
+	d.Now = func() time.Time { return now }
+
+	opts := converter.DefaultOptions()
+	opts.SXClosedAsDeleted = true
+	opts.Now = d.Now
+	situation := func(progress string) []converter.Entity {
+		sx := decodeSituation(t, `
+			<SituationNumber>SX-T</SituationNumber>
+			<Progress>`+progress+`</Progress>
+			<Summary xml:lang="en">Stop closed</Summary>
+			<Affects><StopPoints><AffectedStopPoint><StopPointRef>SOFIA:Quay:Q1</StopPointRef></AffectedStopPoint></StopPoints></Affects>`)
+		ents, err := converter.ConvertSIRI(&siri.ServiceDelivery{SituationExchangeDeliveries: []siri.SituationExchangeDelivery{{
+			Situations: []siri.PtSituationElement{*sx},
+		}}}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ents
+	}
+
+	v1 := d.Update(situation("open"))
+	now = now.Add(time.Minute)
+	d.Update(situation("closed"))
+
+	changes, _ := d.Changes(v1)
+	if len(changes) != 1 || changes[0].Kind != "alert" {
+		t.Fatalf("expected one alert tombstone, got %+v", changes)
+	}
+	if full, _ := d.Changes(0); len(full) != 0 {
+		t.Errorf("closed situation must leave the full dataset, got %v", entityIDs(full))
+	}
+
+	// Both encodings carry is_deleted.
+	msg := converter.BuildDifferentialFeedMessage(changes)
+	b, err := json.Marshal(msg)
+	if err != nil || !strings.Contains(string(b), `"is_deleted":true`) {
+		t.Errorf("JSON tombstone missing: %s %v", b, err)
+	}
+	pbf, err := gtfsrt.MarshalPBF(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, err := gtfsrt.UnmarshalPBFToProto(pbf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := pm.GetEntity()[0]; !e.GetIsDeleted() || e.GetId() != "SX-T" || len(e.GetAlert().GetInformedEntity()) != 1 {
+		t.Errorf("PBF tombstone = %v", e)
+	}
+
+	// Still served within the window, dropped after it.
+	now = now.Add(4 * time.Minute)
+	d.Update(nil)
+	if changes, _ := d.Changes(v1); len(changes) != 1 || !d.Covers(v1) {
+		t.Errorf("tombstone dropped within the retention window")
+	}
+	now = now.Add(2 * time.Minute)
+	d.Update(nil)
+	if d.Len() != 0 || d.Covers(v1) || !d.Covers(d.Version()) {
+		t.Errorf("expected the tombstone to expire (len %d)", d.Len())
+	}
+}
+
+func TestDifferential_SeverityChangeAndFutureVersion(t *testing.T) {
+	alert := func(severity gtfs.Alert_SeverityLevel) []converter.Entity {
+		id := "SX-S"
+		return []converter.Entity{{ID: id, Kind: "alert", Message: &gtfs.FeedEntity{
+			Id:    &id,
+			Alert: &gtfs.Alert{SeverityLevel: severity.Enum()},
+		}}}
+	}
+
+	d := converter.NewDifferential()
+	v1 := d.Update(alert(gtfs.Alert_INFO))
+	d.Update(alert(gtfs.Alert_SEVERE))
+	if changes, _ := d.Changes(v1); len(changes) != 1 {
+		t.Errorf("expected the severity change to be reported, got %v", entityIDs(changes))
+	}
+
+	// A client ahead of the tracker (e.g. after a server restart) reloads.
+	future := d.Version() + 5
+	if d.Covers(future) {
+		t.Error("a future version must not be covered")
+	}
+	if full, version := d.Changes(future); len(full) != 1 || version != d.Version() {
+		t.Errorf("expected the full dataset for a future version, got %v (version %d)", entityIDs(full), version)
+	}
+}
+
+func TestDifferential_UpdateScopedToKinds(t *testing.T) {
+	entity := func(kind, id string) converter.Entity {
+		fe := &gtfs.FeedEntity{Id: &id}
+		switch kind {
+		case "alert":
+			fe.Alert = &gtfs.Alert{}
+		case "trip_update":
+			fe.TripUpdate = &gtfs.TripUpdate{}
+		default:
+			fe.Vehicle = &gtfs.VehiclePosition{}
+		}
+		return converter.Entity{ID: id, Kind: kind, Message: fe}
+	}
+
+	d := converter.NewDifferential()
+	v1 := d.Update([]converter.Entity{entity("trip_update", "T1"), entity("alert", "A1"), entity("vehicle_position", "V1")})
+
+	// A VM-only poll replaces vehicle positions and nothing else.
+	d.Update([]converter.Entity{entity("vehicle_position", "V2")})
+	changes, _ := d.Changes(v1)
+	if got := entityIDs(changes); !reflect.DeepEqual(got, []string{"V1", "V2"}) {
+		t.Errorf("changes after VM poll = %v, want [V1 V2]", got)
+	}
+	for _, e := range changes {
+		if e.Deleted != (e.ID == "V1") {
+			t.Errorf("%s deleted = %v", e.ID, e.Deleted)
+		}
+	}
+
+	// An explicitly scoped empty SX poll deletes the alerts.
+	v3 := d.UpdateKinds(nil, "alert")
+	changes, _ = d.Changes(v3 - 1)
+	if len(changes) != 1 || changes[0].ID != "A1" || !changes[0].Deleted {
+		t.Errorf("expected A1 tombstone, got %v", entityIDs(changes))
+	}
+	if full, _ := d.Changes(0); !reflect.DeepEqual(entityIDs(full), []string{"T1", "V2"}) {
+		t.Errorf("live entities = %v, want [T1 V2]", entityIDs(full))
+	}
+}
@@ -0,0 +1,93 @@
+package converter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/formatter"
+)
+
+func TestConvertSIRI_FacilityMonitoring(t *testing.T) {
+	doc := `<Siri version="2.0" xmlns="http://www.siri.org.uk/siri"><ServiceDelivery>
+		<FacilityMonitoringDelivery>
+			<FacilityCondition>
+				<Facility>
+					<FacilityCode>SOFIA:Facility:LIFT7</FacilityCode>
+					<FacilityClass>lift</FacilityClass>
+					<FacilityLocation><StopPlaceRef>SOFIA:StopPlace:ST1</StopPlaceRef></FacilityLocation>
+				</Facility>
+				<FacilityStatus>
+					<Status>notAvailable</Status>
+					<Description xml:lang="en">Lift to platform 2 under repair</Description>
+				</FacilityStatus>
+				<ValidityPeriod><StartTime>2025-09-12T06:00:00Z</StartTime><EndTime>2025-09-12T18:00:00Z</EndTime></ValidityPeriod>
+			</FacilityCondition>
+			<FacilityCondition>
+				<FacilityRef>ESC1</FacilityRef>
+				<FacilityStatus><Status>available</Status></FacilityStatus>
+			</FacilityCondition>
+			<FacilityCondition>
+				<FacilityRef>ESC2</FacilityRef>
+				<FacilityStatus><Status>notAvailable</Status></FacilityStatus>
+			</FacilityCondition>
+			<FacilityCondition>
+				<Facility>
+					<FacilityCode>ESC3</FacilityCode>
+					<FacilityClass>escalator</FacilityClass>
+					<FacilityLocation><StopPlaceComponentRef>SOFIA:StopPlaceComponent:N1</StopPlaceComponentRef></FacilityLocation>
+				</Facility>
+				<FacilityStatus><Status>notAvailable</Status></FacilityStatus>
+			</FacilityCondition>
+		</FacilityMonitoringDelivery>
+	</ServiceDelivery></Siri>`
+	sd, err := formatter.DecodeSIRI(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stops, err := converter.LoadStopIndex(strings.NewReader("stop_id,parent_station\nST1,\nQ1,ST1\nN1,ST1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := converter.DefaultOptions()
+	opts.Stops = stops
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 12, 0, 0, 0, time.UTC) }
+	var rejected []converter.Rejection
+	opts.OnReject = func(r converter.Rejection) { rejected = append(rejected, r) }
+
+	ents, err := converter.ConvertSIRI(sd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(entityIDs(ents), ","); got != "facility-LIFT7,facility-ESC3" {
+		t.Fatalf("unexpected entities: %v", got)
+	}
+	if len(rejected) != 1 || rejected[0].ID != "facility-ESC2" || rejected[0].Reason != converter.RejectNoInformedEntity {
+		t.Errorf("unexpected rejections: %+v", rejected)
+	}
+	if got := strings.Join(stopIDs(ents[1].Message.Alert.InformedEntity), ","); got != "N1,ST1" {
+		t.Errorf("component informed stops = %s", got)
+	}
+	if ents[0].TTL != 6*time.Hour {
+		t.Errorf("ttl = %v", ents[0].TTL)
+	}
+	alert := ents[0].Message.Alert
+	if *alert.Effect != 11 {
+		t.Errorf("effect = %d, want ACCESSIBILITY_ISSUE", *alert.Effect)
+	}
+	if got := strings.Join(stopIDs(alert.InformedEntity), ","); got != "ST1,Q1,N1" {
+		t.Errorf("informed stops = %s", got)
+	}
+	if got := enText(alert.HeaderText); got != "Elevator out of service" {
+		t.Errorf("header = %q", got)
+	}
+	if got := enText(alert.DescriptionText); got != "Lift to platform 2 under repair" {
+		t.Errorf("description = %q", got)
+	}
+
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 19, 0, 0, 0, time.UTC) }
+	if ents, _ := converter.ConvertSIRI(sd, opts); len(ents) != 1 || ents[0].ID != "facility-ESC3" {
+		t.Errorf("expired facility condition still converted: %v", entityIDs(ents))
+	}
+}
@@ -0,0 +1,72 @@
+package converter_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+func TestVehicleHistory_DerivesBearingAndSpeed(t *testing.T) {
+	opts := converter.DefaultOptions()
+	opts.VehicleHistory = converter.NewVehicleHistory()
+
+	first := vehicleActivity("V1", 42.7000, 23.3000)
+	first.RecordedAtTime = strPtr("2025-09-12T10:00:00Z")
+	if e := converter.MapVMToVehiclePosition(first, opts); e == nil || e.Message.Vehicle.Position.Speed != nil {
+		t.Fatal("first sample should be emitted without derived speed")
+	}
+
+	// ~111 m due north in 10 s
+	second := vehicleActivity("V1", 42.7010, 23.3000)
+	second.RecordedAtTime = strPtr("2025-09-12T10:00:10Z")
+	e := converter.MapVMToVehiclePosition(second, opts)
+	if e == nil {
+		t.Fatal("expected vehicle position")
+	}
+	pos := e.Message.Vehicle.Position
+	if pos.Speed == nil || math.Abs(float64(*pos.Speed)-11.1) > 0.2 {
+		t.Errorf("expected derived speed ~11.1 m/s, got %v", pos.Speed)
+	}
+	if pos.Bearing == nil || math.Abs(float64(*pos.Bearing)) > 0.5 {
+		t.Errorf("expected derived bearing ~0, got %v", pos.Bearing)
+	}
+
+	// Out-of-order sample must not derive anything
+	late := vehicleActivity("V1", 42.6990, 23.3000)
+	late.RecordedAtTime = strPtr("2025-09-12T10:00:05Z")
+	e = converter.MapVMToVehiclePosition(late, opts)
+	if e == nil || e.Message.Vehicle.Position.Speed != nil {
+		t.Error("expected no derived speed for out-of-order sample")
+	}
+
+	// Jitter below MinDistance reports a stationary vehicle
+	jitter := vehicleActivity("V1", 42.70101, 23.30001)
+	jitter.RecordedAtTime = strPtr("2025-09-12T10:00:20Z")
+	e = converter.MapVMToVehiclePosition(jitter, opts)
+	if e == nil || e.Message.Vehicle.Position.Speed == nil || *e.Message.Vehicle.Position.Speed != 0 {
+		t.Error("expected zero speed for GPS jitter")
+	}
+}
+
+func TestConvertSIRI_PrunesVehicleHistory(t *testing.T) {
+	opts := converter.DefaultOptions()
+	opts.VehicleHistory = converter.NewVehicleHistory()
+
+	va := vehicleActivity("V1", 42.7000, 23.3000)
+	va.RecordedAtTime = strPtr("2025-09-12T10:00:00Z")
+	converter.MapVMToVehiclePosition(va, opts)
+	if n := opts.VehicleHistory.Len(); n != 1 {
+		t.Fatalf("expected 1 tracked vehicle, got %d", n)
+	}
+
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 10, 5, 0, 0, time.UTC) }
+	if _, err := converter.ConvertSIRI(&siri.ServiceDelivery{}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := opts.VehicleHistory.Len(); n != 0 {
+		t.Errorf("expected stale sample to be pruned, got %d", n)
+	}
+}
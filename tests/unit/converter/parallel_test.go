@@ -0,0 +1,92 @@
+package converter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+// nationalET builds an ET delivery with n journeys of 20 calls each.
+func nationalET(n int) *siri.ServiceDelivery {
+	base := time.Date(2025, 9, 12, 5, 0, 0, 0, time.UTC)
+	journeys := make([]siri.EstimatedVehicleJourney, n)
+	for i := range journeys {
+		start := base.Add(time.Duration(i%600) * time.Minute)
+		evj := siri.EstimatedVehicleJourney{
+			LineRef:                  strPtr(fmt.Sprintf("SOFIA:Line:%d", i%120)),
+			FramedVehicleJourneyRef:  &siri.FramedVehicleJourneyRef{DataFrameRef: strPtr("2025-09-12"), DatedVehicleJourneyRef: strPtr(fmt.Sprintf("SOFIA:ServiceJourney:T%d", i))},
+			OriginAimedDepartureTime: strPtr(start.Format(time.RFC3339)),
+			RecordedAtTime:           strPtr(start.Format(time.RFC3339)),
+		}
+		for c := 0; c < 20; c++ {
+			aimed := start.Add(time.Duration(c*2) * time.Minute)
+			order := int32(c + 1)
+			evj.EstimatedCalls = append(evj.EstimatedCalls, siri.EstimatedCall{
+				StopPointRef:          strPtr(fmt.Sprintf("SOFIA:Quay:Q%d", c)),
+				Order:                 &order,
+				AimedArrivalTime:      strPtr(aimed.Format(time.RFC3339)),
+				ExpectedArrivalTime:   strPtr(aimed.Add(time.Minute).Format(time.RFC3339)),
+				AimedDepartureTime:    strPtr(aimed.Format(time.RFC3339)),
+				ExpectedDepartureTime: strPtr(aimed.Add(time.Minute).Format(time.RFC3339)),
+			})
+		}
+		journeys[i] = evj
+	}
+	return &siri.ServiceDelivery{EstimatedTimetableDeliveries: []siri.EstimatedTimetableDelivery{{
+		EstimatedJourneyVersionFrames: []siri.EstimatedJourneyVersionFrame{{EstimatedVehicleJourneys: journeys}},
+	}}}
+}
+
+func fixedOptions(workers int) converter.Options {
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 4, 0, 0, 0, time.UTC) }
+	opts.Workers = workers
+	return opts
+}
+
+func TestConvertSIRI_ParallelMatchesSequential(t *testing.T) {
+	sd := nationalET(500)
+	want, err := converter.ConvertSIRI(sd, fixedOptions(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := converter.ConvertSIRIContext(context.Background(), sd, fixedOptions(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 500 || !reflect.DeepEqual(got, want) {
+		t.Errorf("parallel output differs from sequential (%d vs %d entities)", len(got), len(want))
+	}
+}
+
+func TestConvertSIRIContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, workers := range []int{0, 4} {
+		ents, err := converter.ConvertSIRIContext(ctx, nationalET(100), fixedOptions(workers))
+		if !errors.Is(err, context.Canceled) || ents != nil {
+			t.Errorf("workers=%d: got %d entities, err %v", workers, len(ents), err)
+		}
+	}
+}
+
+func benchmarkConvert(b *testing.B, workers int) {
+	sd := nationalET(50000)
+	opts := fixedOptions(workers)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := converter.ConvertSIRI(sd, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConvertSIRI_Sequential(b *testing.B) { benchmarkConvert(b, 0) }
+func BenchmarkConvertSIRI_Workers4(b *testing.B)   { benchmarkConvert(b, 4) }
+func BenchmarkConvertSIRI_Workers8(b *testing.B)   { benchmarkConvert(b, 8) }
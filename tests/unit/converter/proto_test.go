@@ -0,0 +1,139 @@
+package converter_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/formatter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+func TestConvertSIRIToProto(t *testing.T) {
+	sx := decodeSituation(t, `
+		<SituationNumber>SX-P</SituationNumber>
+		<ValidityPeriod><StartTime>2025-09-12T06:00:00Z</StartTime></ValidityPeriod>
+		<Severity>severe</Severity>
+		<Summary xml:lang="en">Trip cancelled</Summary>
+		<Affects><VehicleJourneys><AffectedVehicleJourney>
+			<FramedVehicleJourneyRef><DataFrameRef>2025-09-12</DataFrameRef><DatedVehicleJourneyRef>T1</DatedVehicleJourneyRef></FramedVehicleJourneyRef>
+		</AffectedVehicleJourney></VehicleJourneys></Affects>`)
+	sd := vmDelivery(journeyActivity("T1", "U1", "2025-09-12T10:00:00Z"))
+	sd.SituationExchangeDeliveries = []siri.SituationExchangeDelivery{{Situations: []siri.PtSituationElement{*sx}}}
+
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 10, 0, 0, 0, time.UTC) }
+	pm, err := converter.ConvertSIRIToProto(sd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pm.GetHeader().GetTimestamp() == 0 || pm.GetHeader().GetIncrementality() != gtfs.FeedHeader_FULL_DATASET {
+		t.Errorf("unexpected header: %v", pm.GetHeader())
+	}
+	if len(pm.GetEntity()) != 2 {
+		t.Fatalf("expected vehicle and alert, got %d entities", len(pm.GetEntity()))
+	}
+
+	vp := pm.GetEntity()[0].GetVehicle()
+	if vp.GetTimestamp() != 1757671200 || vp.GetVehicle().GetId() != "U1" || vp.GetTrip().GetTripId() != "T1" {
+		t.Errorf("unexpected vehicle: %v", vp)
+	}
+	alert := pm.GetEntity()[1].GetAlert()
+	if alert.GetSeverityLevel() != gtfs.Alert_SEVERE {
+		t.Errorf("severity_level = %v, want SEVERE", alert.GetSeverityLevel())
+	}
+	if ie := alert.GetInformedEntity(); len(ie) != 1 || ie[0].GetTrip().GetStartDate() != "20250912" {
+		t.Errorf("unexpected informed entities: %v", ie)
+	}
+
+	b, err := gtfsrt.MarshalProtoJSON(pm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Entity []struct {
+			Alert *struct {
+				SeverityLevel int `json:"severity_level"`
+			} `json:"alert"`
+		} `json:"entity"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Entity) != 2 || doc.Entity[1].Alert == nil || doc.Entity[1].Alert.SeverityLevel != int(gtfs.Alert_SEVERE) {
+		t.Errorf("unexpected JSON: %s", b)
+	}
+}
+
+func TestMarshalProtoJSON_ExtensionFields(t *testing.T) {
+	sx := decodeSituation(t, `
+		<SituationNumber>SX-X</SituationNumber>
+		<ReasonName xml:lang="en">Track works</ReasonName>
+		<Summary xml:lang="en">Line 94 diverted</Summary>
+		<Images><Image><ImageRef>https://example.org/map.png</ImageRef></Image></Images>
+		<Consequences><Consequence><Condition>diverted</Condition></Consequence></Consequences>`)
+	doc := `<Siri version="2.0" xmlns="http://www.siri.org.uk/siri"><ServiceDelivery>` + productionTimetable + estimatedT1 + `</ServiceDelivery></Siri>`
+	sd, err := formatter.DecodeSIRI(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd.SituationExchangeDeliveries = []siri.SituationExchangeDelivery{{Situations: []siri.PtSituationElement{*sx}}}
+
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 9, 5, 0, 0, time.UTC) }
+	pm, err := converter.ConvertSIRIToProto(sd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := gtfsrt.MarshalProtoJSON(pm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type translated struct {
+		Translation []struct {
+			Text string `json:"text"`
+		} `json:"translation"`
+	}
+	var feed struct {
+		Entity []struct {
+			TripUpdate *struct {
+				TripProperties struct {
+					TripHeadsign string `json:"trip_headsign"`
+				} `json:"trip_properties"`
+			} `json:"trip_update"`
+			Alert *struct {
+				CauseDetail  translated `json:"cause_detail"`
+				EffectDetail translated `json:"effect_detail"`
+				Image        struct {
+					LocalizedImage []struct {
+						URL string `json:"url"`
+					} `json:"localized_image"`
+				} `json:"image"`
+			} `json:"alert"`
+		} `json:"entity"`
+	}
+	if err := json.Unmarshal(b, &feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Entity) != 2 || feed.Entity[0].TripUpdate == nil || feed.Entity[1].Alert == nil {
+		t.Fatalf("expected trip update and alert, got %s", b)
+	}
+	if got := feed.Entity[0].TripUpdate.TripProperties.TripHeadsign; got != "Mladost 1" {
+		t.Errorf("trip_headsign = %q", got)
+	}
+	a := feed.Entity[1].Alert
+	if len(a.CauseDetail.Translation) != 2 || a.CauseDetail.Translation[1].Text != "Track works" {
+		t.Errorf("unexpected cause_detail: %+v", a.CauseDetail)
+	}
+	if len(a.EffectDetail.Translation) != 2 || a.EffectDetail.Translation[1].Text != "Diverted" {
+		t.Errorf("unexpected effect_detail: %+v", a.EffectDetail)
+	}
+	if len(a.Image.LocalizedImage) != 1 || a.Image.LocalizedImage[0].URL != "https://example.org/map.png" {
+		t.Errorf("unexpected image: %+v", a.Image)
+	}
+}
@@ -0,0 +1,109 @@
+package converter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/formatter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+func stopVisit(stop, order, expected, location string) string {
+	return `<MonitoredStopVisit>
+		<RecordedAtTime>2025-09-12T09:05:00Z</RecordedAtTime>
+		<MonitoringRef>` + stop + `</MonitoringRef>
+		<MonitoredVehicleJourney>
+			<LineRef>SOFIA:Line:94</LineRef>
+			<FramedVehicleJourneyRef><DataFrameRef>2025-09-12</DataFrameRef><DatedVehicleJourneyRef>SOFIA:ServiceJourney:T1</DatedVehicleJourneyRef></FramedVehicleJourneyRef>
+			<VehicleRef>V1</VehicleRef>
+			<OriginAimedDepartureTime>2025-09-12T09:00:00Z</OriginAimedDepartureTime>` + location + `
+			<MonitoredCall>
+				<StopPointRef>SOFIA:Quay:` + stop + `</StopPointRef>
+				<Order>` + order + `</Order>
+				<ExpectedArrivalTime>` + expected + `</ExpectedArrivalTime>
+			</MonitoredCall>
+		</MonitoredVehicleJourney>
+	</MonitoredStopVisit>`
+}
+
+func TestConvertSIRI_StopMonitoring(t *testing.T) {
+	location := `<VehicleLocation><Longitude>23.32</Longitude><Latitude>42.69</Latitude></VehicleLocation>`
+	doc := `<Siri version="2.0" xmlns="http://www.siri.org.uk/siri"><ServiceDelivery>
+		<StopMonitoringDelivery>` + stopVisit("Q3", "3", "2025-09-12T09:20:00Z", location) + `</StopMonitoringDelivery>
+		<StopMonitoringDelivery>` + stopVisit("Q2", "2", "2025-09-12T09:10:00Z", location) + `</StopMonitoringDelivery>
+	</ServiceDelivery></Siri>`
+	sd, err := formatter.DecodeSIRI(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 9, 6, 0, 0, time.UTC) }
+
+	ents, err := converter.ConvertSIRI(sd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, e := range ents {
+		kinds = append(kinds, e.Kind+":"+e.ID)
+	}
+	if got := strings.Join(kinds, ","); got != "trip_update:T1-20250912,vehicle_position:T1-20250912" {
+		t.Fatalf("unexpected entities: %s", got)
+	}
+
+	tu := ents[0].Message.TripUpdate
+	if tu.Trip.GetTripId() != "T1" || tu.Trip.GetRouteId() != "94" || tu.Vehicle.GetId() != "V1" {
+		t.Errorf("unexpected trip: %+v", tu.Trip)
+	}
+	if len(tu.StopTimeUpdate) != 2 {
+		t.Fatalf("expected 2 stop time updates, got %d", len(tu.StopTimeUpdate))
+	}
+	first, second := tu.StopTimeUpdate[0], tu.StopTimeUpdate[1]
+	if first.GetStopId() != "Q2" || first.GetStopSequence() != 1 || second.GetStopId() != "Q3" || second.GetStopSequence() != 2 {
+		t.Errorf("stops not ordered: %+v, %+v", first, second)
+	}
+	if first.Arrival.GetTime() != 1757668200 {
+		t.Errorf("unexpected arrival: %+v", first.Arrival)
+	}
+	if ents[0].TTL != 14*time.Minute {
+		t.Errorf("ttl = %v", ents[0].TTL)
+	}
+}
+
+func TestConvertSIRI_StopMonitoringYieldsToET(t *testing.T) {
+	doc := `<Siri version="2.0" xmlns="http://www.siri.org.uk/siri"><ServiceDelivery>
+		<StopMonitoringDelivery>` + stopVisit("Q2", "2", "2025-09-12T09:10:00Z", "") + `</StopMonitoringDelivery>
+	</ServiceDelivery></Siri>`
+	sd, err := formatter.DecodeSIRI(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd.EstimatedTimetableDeliveries = []siri.EstimatedTimetableDelivery{{
+		EstimatedJourneyVersionFrames: []siri.EstimatedJourneyVersionFrame{{
+			EstimatedVehicleJourneys: []siri.EstimatedVehicleJourney{
+				estimatedJourney("T1", "2025-09-12T09:10:00Z", "2025-09-12T09:12:00Z"),
+			},
+		}},
+	}}
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 9, 6, 0, 0, time.UTC) }
+
+	ents, err := converter.ConvertSIRI(sd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range ents {
+		if e.Kind == "trip_update" {
+			ids = append(ids, e.ID)
+		}
+	}
+	if got := strings.Join(ids, ","); got != "T1-20250912" {
+		t.Fatalf("expected a single trip update, got %s", got)
+	}
+	if stu := ents[0].Message.TripUpdate.StopTimeUpdate; len(stu) != 1 || stu[0].GetStopId() != "Q1" {
+		t.Errorf("expected the ET trip update to win, got %+v", stu)
+	}
+}
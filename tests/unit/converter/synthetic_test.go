@@ -0,0 +1,159 @@
+package converter_test
+
+import (
+	"testing"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func estimatedJourney(trip string, aimed, expected string) siri.EstimatedVehicleJourney {
+	return siri.EstimatedVehicleJourney{
+		LineRef:                  strPtr("SOFIA:Line:94"),
+		FramedVehicleJourneyRef:  &siri.FramedVehicleJourneyRef{DataFrameRef: strPtr("2025-09-12"), DatedVehicleJourneyRef: strPtr(trip)},
+		OriginAimedDepartureTime: strPtr("2025-09-12T09:00:00Z"),
+		EstimatedCalls: []siri.EstimatedCall{{
+			StopPointRef:        strPtr("SOFIA:Quay:Q1"),
+			AimedArrivalTime:    strPtr(aimed),
+			ExpectedArrivalTime: strPtr(expected),
+		}},
+	}
+}
+
+func TestConvertSIRI_SyntheticAlerts(t *testing.T) {
+	cancelled := estimatedJourney("T1", "2025-09-12T09:10:00Z", "2025-09-12T09:10:00Z")
+	cancelled.Cancellation = boolPtr(true)
+	late := estimatedJourney("T2", "2025-09-12T09:10:00Z", "2025-09-12T09:30:00Z")
+	onTime := estimatedJourney("T3", "2025-09-12T09:10:00Z", "2025-09-12T09:12:00Z")
+	covered := estimatedJourney("T4", "2025-09-12T09:10:00Z", "2025-09-12T09:40:00Z")
+
+	sd := &siri.ServiceDelivery{
+		EstimatedTimetableDeliveries: []siri.EstimatedTimetableDelivery{{
+			EstimatedJourneyVersionFrames: []siri.EstimatedJourneyVersionFrame{{
+				EstimatedVehicleJourneys: []siri.EstimatedVehicleJourney{cancelled, late, onTime, covered},
+			}},
+		}},
+		SituationExchangeDeliveries: []siri.SituationExchangeDelivery{{
+			Situations: []siri.PtSituationElement{{
+				SituationNumber: strPtr("SX-T4"),
+				Affects: &siri.Affects{VehicleJourneys: []siri.AffectedVehicleJourney{{
+					FramedVehicleJourneyRef: &siri.FramedVehicleJourneyRef{DatedVehicleJourneyRef: strPtr("T4")},
+				}}},
+			}},
+		}},
+		// A connection alert informing T2 must not replace its delay alert.
+		ConnectionMonitoringFeederDeliveries: []siri.ConnectionMonitoringFeederDelivery{{
+			MonitoredFeederArrivalCancellations: []siri.MonitoredFeederArrivalCancellation{{
+				InterchangeRef: strPtr("IC1"),
+				StopPointRef:   strPtr("SOFIA:Quay:Q1"),
+			}},
+		}},
+	}
+
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 9, 0, 0, 0, time.UTC) }
+	opts.SyntheticAlerts = converter.DefaultSyntheticAlerts()
+	opts.ETCancellations = true
+	opts.Interchanges = map[string]converter.Interchange{"IC1": {DistributorTripID: "T2"}}
+	ents, err := converter.ConvertSIRI(sd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := map[string]*gtfs.Alert{}
+	for _, e := range ents {
+		if e.Kind == "trip_update" && e.ID == "T1-20250912" {
+			if e.Message.TripUpdate.Trip.GetScheduleRelationship() != gtfs.TripDescriptor_CANCELED {
+				t.Errorf("cancelled trip not marked CANCELED")
+			}
+		}
+		if e.Kind == "alert" {
+			alerts[e.ID] = e.Message.Alert
+		}
+	}
+	if len(alerts) != 4 || alerts["connection-IC1"] == nil {
+		t.Fatalf("unexpected alerts: %v", entityIDs(ents))
+	}
+	if a := alerts["cancelled-T1-20250912"]; a.GetEffect() != gtfs.Alert_NO_SERVICE {
+		t.Errorf("missing NO_SERVICE alert for T1")
+	} else if got := enText(a.HeaderText); got != "Line 94: 09:00 trip cancelled" {
+		t.Errorf("header = %q", got)
+	}
+	if a := alerts["delayed-T2-20250912"]; a.GetEffect() != gtfs.Alert_SIGNIFICANT_DELAYS {
+		t.Errorf("missing SIGNIFICANT_DELAYS alert for T2")
+	} else if got := enText(a.HeaderText); got != "Line 94: 20 min delay" {
+		t.Errorf("header = %q", got)
+	}
+	if alerts["SX-T4"] == nil {
+		t.Errorf("missing SX alert")
+	}
+}
+
+func TestMapVMToAlert_Delay(t *testing.T) {
+	va := journeyActivity("T1", "V1", "2025-09-12T10:00:00Z")
+	va.MonitoredVehicleJourney.LineRef = strPtr("SOFIA:Line:94")
+	va.MonitoredVehicleJourney.Delay = strPtr("PT25M10S")
+
+	opts := converter.DefaultOptions()
+	if converter.MapVMToAlert(&va, opts) != nil {
+		t.Fatal("alert generated without SyntheticAlerts")
+	}
+	opts.SyntheticAlerts = converter.DefaultSyntheticAlerts()
+	e := converter.MapVMToAlert(&va, opts)
+	if e == nil {
+		t.Fatal("expected alert")
+	}
+	if e.ID != "delayed-T1-20250912" {
+		t.Errorf("id = %q", e.ID)
+	}
+	if got := enText(e.Message.Alert.DescriptionText); got != "The 09:00 trip on line 94 is running about 25 minutes late." {
+		t.Errorf("description = %q", got)
+	}
+}
+
+func TestConvertSIRI_SyntheticAlertsSuppressed(t *testing.T) {
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 9, 0, 0, 0, time.UTC) }
+	opts.SyntheticAlerts = converter.DefaultSyntheticAlerts()
+	opts.DropNullIsland = true
+
+	// A route-level situation covers every trip on line 94.
+	late := estimatedJourney("T2", "2025-09-12T09:10:00Z", "2025-09-12T09:30:00Z")
+	// A delayed vehicle whose position is rejected raises no alert.
+	va := journeyActivity("T5", "V5", "2025-09-12T09:00:00Z")
+	va.MonitoredVehicleJourney.VehicleLocation = &siri.Location{}
+	va.MonitoredVehicleJourney.Delay = strPtr("PT30M")
+	sd := &siri.ServiceDelivery{
+		EstimatedTimetableDeliveries: []siri.EstimatedTimetableDelivery{{
+			EstimatedJourneyVersionFrames: []siri.EstimatedJourneyVersionFrame{{
+				EstimatedVehicleJourneys: []siri.EstimatedVehicleJourney{late},
+			}},
+		}},
+		VehicleMonitoringDeliveries: []siri.VehicleMonitoringDelivery{{VehicleActivities: []siri.VehicleActivity{va}}},
+		SituationExchangeDeliveries: []siri.SituationExchangeDelivery{{
+			Situations: []siri.PtSituationElement{{
+				SituationNumber: strPtr("SX-94"),
+				Affects: &siri.Affects{Networks: []siri.AffectedNetwork{{
+					AffectedLines: []siri.AffectedLine{{LineRef: strPtr("SOFIA:Line:94")}},
+				}}},
+			}},
+		}},
+	}
+	ents, err := converter.ConvertSIRI(sd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := entityIDs(ents); len(ids) != 2 || ids[1] != "SX-94" {
+		t.Errorf("expected the trip update and the SX alert only, got %v", ids)
+	}
+	for _, e := range ents {
+		if e.Kind == "trip_update" && e.Message.TripUpdate.Trip.ScheduleRelationship != nil && *e.Message.TripUpdate.Trip.ScheduleRelationship != 0 {
+			t.Errorf("unexpected schedule relationship without ETCancellations")
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package converter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gtfs "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/formatter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/gtfsrt"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+const productionTimetable = `<ProductionTimetableDelivery>
+	<DatedTimetableVersionFrame>
+		<LineRef>SOFIA:Line:94</LineRef>
+		<DirectionRef>inbound</DirectionRef>
+		<DatedVehicleJourney>
+			<DatedVehicleJourneyCode>SOFIA:ServiceJourney:T1</DatedVehicleJourneyCode>
+			<VehicleMode>tram</VehicleMode>
+			<OperatorRef>SOFIA:Operator:STT</OperatorRef>
+			<DestinationDisplay>Mladost 1</DestinationDisplay>
+			<DatedCalls>
+				<DatedCall><StopPointRef>SOFIA:Quay:Q1</StopPointRef><Order>1</Order><AimedDepartureTime>2025-09-12T09:00:00Z</AimedDepartureTime></DatedCall>
+				<DatedCall><StopPointRef>SOFIA:Quay:Q2</StopPointRef><Order>2</Order><AimedArrivalTime>2025-09-12T09:10:00Z</AimedArrivalTime></DatedCall>
+			</DatedCalls>
+		</DatedVehicleJourney>
+		<DatedVehicleJourney>
+			<DatedVehicleJourneyCode>SOFIA:ServiceJourney:X1</DatedVehicleJourneyCode>
+			<ExtraJourney>true</ExtraJourney>
+			<DatedCalls>
+				<DatedCall><StopPointRef>SOFIA:Quay:Q1</StopPointRef><Order>1</Order><AimedDepartureTime>2025-09-12T10:00:00Z</AimedDepartureTime></DatedCall>
+				<DatedCall><StopPointRef>SOFIA:Quay:Q2</StopPointRef><Order>2</Order><AimedArrivalTime>2025-09-12T10:10:00Z</AimedArrivalTime></DatedCall>
+			</DatedCalls>
+		</DatedVehicleJourney>
+	</DatedTimetableVersionFrame>
+</ProductionTimetableDelivery>`
+
+const estimatedT1 = `<EstimatedTimetableDelivery><EstimatedJourneyVersionFrame><EstimatedVehicleJourney>
+	<FramedVehicleJourneyRef><DataFrameRef>2025-09-12</DataFrameRef><DatedVehicleJourneyRef>SOFIA:ServiceJourney:T1</DatedVehicleJourneyRef></FramedVehicleJourneyRef>
+	<OriginAimedDepartureTime>2025-09-12T09:00:00Z</OriginAimedDepartureTime>
+	<EstimatedCalls><EstimatedCall><StopPointRef>SOFIA:Quay:Q2</StopPointRef><Order>2</Order><AimedArrivalTime>2025-09-12T09:10:00Z</AimedArrivalTime><ExpectedArrivalTime>2025-09-12T09:12:00Z</ExpectedArrivalTime></EstimatedCall></EstimatedCalls>
+</EstimatedVehicleJourney></EstimatedJourneyVersionFrame></EstimatedTimetableDelivery>`
+
+func TestConvertSIRI_ProductionTimetable(t *testing.T) {
+	doc := `<Siri version="2.0" xmlns="http://www.siri.org.uk/siri"><ServiceDelivery>` + productionTimetable + estimatedT1 + `</ServiceDelivery></Siri>`
+	sd, err := formatter.DecodeSIRI(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 9, 5, 0, 0, time.UTC) }
+	opts.PTEmitExtraJourneys = true
+
+	ents, err := converter.ConvertSIRI(sd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(entityIDs(ents), ","); got != "T1-20250912,X1-20250912" {
+		t.Fatalf("unexpected entities: %s", got)
+	}
+
+	tu := ents[0].Message.TripUpdate
+	if tu.Trip.GetRouteId() != "94" || tu.Trip.DirectionId == nil || tu.Trip.GetDirectionId() != 1 {
+		t.Errorf("route/direction not filled from PT: %+v", tu.Trip)
+	}
+	if gtfsrt.TripHeadsign(tu.TripProperties) != "Mladost 1" {
+		t.Errorf("headsign not filled from PT: %+v", tu.TripProperties)
+	}
+
+	added := ents[1].Message.TripUpdate
+	if added.Trip.GetScheduleRelationship() != gtfs.TripDescriptor_ADDED {
+		t.Errorf("extra journey not ADDED")
+	}
+	if len(added.StopTimeUpdate) != 2 || added.StopTimeUpdate[1].Arrival.GetTime() != 1757671800 {
+		t.Errorf("unexpected planned stop times: %+v", added.StopTimeUpdate)
+	}
+
+	// direction_id and trip_headsign survive protobuf encoding
+	b, err := gtfsrt.MarshalPBF(converter.BuildFeedMessage(ents[:1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "Mladost 1") {
+		t.Error("trip_headsign missing from PBF")
+	}
+}
+
+func TestConvertSIRI_TimetableOperatorAndMode(t *testing.T) {
+	late := strings.Replace(estimatedT1, "2025-09-12T09:12:00Z", "2025-09-12T09:30:00Z", 1)
+	doc := `<Siri version="2.0" xmlns="http://www.siri.org.uk/siri"><ServiceDelivery>` + productionTimetable + late + `</ServiceDelivery></Siri>`
+	sd, err := formatter.DecodeSIRI(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return time.Date(2025, 9, 12, 9, 5, 0, 0, time.UTC) }
+	opts.SyntheticAlerts = converter.DefaultSyntheticAlerts()
+
+	ents, err := converter.ConvertSIRI(sd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sel *gtfs.EntitySelector
+	for _, e := range ents {
+		if e.ID == "delayed-T1-20250912" {
+			sel = e.Message.Alert.InformedEntity[0]
+		}
+	}
+	if sel == nil {
+		t.Fatalf("expected a delay alert, got %v", entityIDs(ents))
+	}
+	if sel.GetAgencyId() != "STT" || sel.RouteType == nil || sel.GetRouteType() != 0 {
+		t.Errorf("operator and vehicle mode not informed: %+v", sel)
+	}
+}
+
+func TestTimetable_SharedAcrossConversions(t *testing.T) {
+	now := time.Date(2025, 9, 12, 9, 5, 0, 0, time.UTC)
+	tt := converter.NewTimetable()
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return now }
+	opts.Timetable = tt
+
+	pt, err := formatter.DecodeSIRI(strings.NewReader(`<Siri xmlns="http://www.siri.org.uk/siri"><ServiceDelivery>` + productionTimetable + `</ServiceDelivery></Siri>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ents, _ := converter.ConvertSIRI(pt, opts); len(ents) != 0 {
+		t.Errorf("PT alone produced entities without PTEmitExtraJourneys: %v", entityIDs(ents))
+	}
+	if tt.Len() != 2 {
+		t.Fatalf("timetable holds %d journeys", tt.Len())
+	}
+
+	et, err := formatter.DecodeSIRI(strings.NewReader(`<Siri xmlns="http://www.siri.org.uk/siri"><ServiceDelivery>` + estimatedT1 + `</ServiceDelivery></Siri>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ents, _ := converter.ConvertSIRI(et, opts)
+	if len(ents) != 1 || ents[0].Message.TripUpdate.Trip.GetRouteId() != "94" {
+		t.Errorf("ET not completed from shared timetable")
+	}
+
+	// T1 ends 09:10 and X1 10:10; conversions forget them after Retention.
+	now = time.Date(2025, 9, 12, 9, 10, 0, 0, time.UTC).Add(tt.Retention + time.Minute)
+	if _, err := converter.ConvertSIRI(&siri.ServiceDelivery{}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if tt.Len() != 1 {
+		t.Errorf("expected only X1 to be kept, got %d journeys", tt.Len())
+	}
+	tt.Prune(time.Date(2025, 9, 12, 11, 0, 0, 0, time.UTC))
+	if tt.Len() != 0 {
+		t.Errorf("prune kept %d journeys", tt.Len())
+	}
+}
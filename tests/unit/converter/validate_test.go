@@ -0,0 +1,149 @@
+package converter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+func strPtr(s string) *string { return &s }
+
+func vehicleActivity(ref string, lat, lon float64) *siri.VehicleActivity {
+	return &siri.VehicleActivity{
+		MonitoredVehicleJourney: &siri.MonitoredVehicleJourney{
+			VehicleRef:      strPtr(ref),
+			VehicleLocation: &siri.Location{Latitude: lat, Longitude: lon},
+		},
+	}
+}
+
+func TestMapVMToVehiclePosition_Validation(t *testing.T) {
+	t.Run("null island is rejected and reported", func(t *testing.T) {
+		var rejected []converter.Rejection
+		opts := converter.DefaultOptions()
+		opts.OnReject = func(r converter.Rejection) { rejected = append(rejected, r) }
+
+		if e := converter.MapVMToVehiclePosition(vehicleActivity("V1", 0, 0), opts); e != nil {
+			t.Fatal("expected null-island position to be dropped")
+		}
+		if len(rejected) != 1 || rejected[0].Reason != converter.RejectNullIsland || rejected[0].ID != "V1" {
+			t.Errorf("unexpected rejections: %+v", rejected)
+		}
+	})
+
+	t.Run("missing location is rejected and reported", func(t *testing.T) {
+		var rejected []converter.Rejection
+		opts := converter.DefaultOptions()
+		opts.OnReject = func(r converter.Rejection) { rejected = append(rejected, r) }
+
+		va := vehicleActivity("V1", 42.7, 23.3)
+		va.MonitoredVehicleJourney.VehicleLocation = nil
+		if e := converter.MapVMToVehiclePosition(va, opts); e != nil {
+			t.Fatal("expected activity without location to be dropped")
+		}
+		if len(rejected) != 1 || rejected[0].Reason != converter.RejectMissingPosition {
+			t.Errorf("unexpected rejections: %+v", rejected)
+		}
+	})
+
+	t.Run("invalid latitude is rejected", func(t *testing.T) {
+		if e := converter.MapVMToVehiclePosition(vehicleActivity("V1", 95, 10), converter.DefaultOptions()); e != nil {
+			t.Fatal("expected out-of-range latitude to be dropped")
+		}
+	})
+
+	t.Run("bounding box", func(t *testing.T) {
+		opts := converter.DefaultOptions()
+		opts.BoundingBox = &converter.BoundingBox{MinLat: 42.5, MinLon: 23.1, MaxLat: 42.8, MaxLon: 23.5}
+		if e := converter.MapVMToVehiclePosition(vehicleActivity("V1", 42.7, 23.3), opts); e == nil {
+			t.Error("expected position inside bounding box to be kept")
+		}
+		if e := converter.MapVMToVehiclePosition(vehicleActivity("V1", 59.0, 10.0), opts); e != nil {
+			t.Error("expected position outside bounding box to be dropped")
+		}
+	})
+
+	t.Run("bearing is normalized", func(t *testing.T) {
+		va := vehicleActivity("V1", 42.7, 23.3)
+		b := float32(-90)
+		va.MonitoredVehicleJourney.Bearing = &b
+		e := converter.MapVMToVehiclePosition(va, converter.DefaultOptions())
+		if e == nil || e.Message.Vehicle.Position.Bearing == nil {
+			t.Fatal("expected bearing to be set")
+		}
+		if got := *e.Message.Vehicle.Position.Bearing; got != 270 {
+			t.Errorf("expected bearing 270, got %v", got)
+		}
+
+		opts := converter.DefaultOptions()
+		opts.NormalizeBearing = false
+		e = converter.MapVMToVehiclePosition(va, opts)
+		if e == nil || e.Message.Vehicle.Position.Bearing != nil {
+			t.Error("expected out-of-range bearing to be dropped without normalization")
+		}
+	})
+
+	t.Run("velocity in km/h is converted", func(t *testing.T) {
+		va := vehicleActivity("V1", 42.7, 23.3)
+		v := 36.0
+		va.MonitoredVehicleJourney.Velocity = &v
+		opts := converter.DefaultOptions()
+		opts.VelocityUnit = converter.SpeedKilometersPerHour
+		e := converter.MapVMToVehiclePosition(va, opts)
+		if e == nil || e.Message.Vehicle.Position.Speed == nil {
+			t.Fatal("expected speed to be set")
+		}
+		if got := *e.Message.Vehicle.Position.Speed; got != 10 {
+			t.Errorf("expected speed 10 m/s, got %v", got)
+		}
+	})
+}
+
+func TestMapVMToVehiclePosition_Staleness(t *testing.T) {
+	now, _ := siri.ParseISOTime("2025-09-12T10:00:00Z")
+	opts := converter.DefaultOptions()
+	opts.Now = func() time.Time { return now }
+	opts.VMMaxAge = 10 * time.Minute
+	opts.VMFutureTolerance = time.Minute
+	opts.DropExpiredVehicles = true
+
+	var reasons []string
+	opts.OnReject = func(r converter.Rejection) { reasons = append(reasons, r.Reason) }
+
+	tests := []struct {
+		name       string
+		recorded   string
+		validUntil string
+		reason     string
+	}{
+		{name: "fresh", recorded: "2025-09-12T09:59:30Z", validUntil: "2025-09-12T10:05:00Z"},
+		{name: "stale", recorded: "2025-09-12T07:00:00Z", reason: converter.RejectStale},
+		{name: "clock skew", recorded: "2025-09-12T10:05:00Z", reason: converter.RejectFutureTimestamp},
+		{name: "expired", recorded: "2025-09-12T09:59:00Z", validUntil: "2025-09-12T09:59:30Z", reason: converter.RejectExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons = nil
+			va := vehicleActivity("V1", 42.7, 23.3)
+			va.RecordedAtTime = strPtr(tt.recorded)
+			if tt.validUntil != "" {
+				va.ValidUntilTime = strPtr(tt.validUntil)
+			}
+			e := converter.MapVMToVehiclePosition(va, opts)
+			if tt.reason == "" {
+				if e == nil {
+					t.Fatalf("expected fresh vehicle to be kept, rejected: %v", reasons)
+				}
+				if e.TTL != 5*time.Minute {
+					t.Errorf("expected TTL from ValidUntilTime relative to injected clock, got %v", e.TTL)
+				}
+				return
+			}
+			if e != nil || len(reasons) != 1 || reasons[0] != tt.reason {
+				t.Errorf("expected rejection %q, got entity=%v reasons=%v", tt.reason, e != nil, reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package formatter_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/formatter"
+)
+
+func TestDecodeSIRIJSON_Capitalized(t *testing.T) {
+	doc := `{"Siri": {"ServiceDelivery": {
+		"VehicleMonitoringDelivery": [{
+			"VehicleActivity": [{
+				"RecordedAtTime": "2025-09-12T10:00:00+00:00",
+				"MonitoredVehicleJourney": {
+					"LineRef": "TEST:Line:1",
+					"FramedVehicleJourneyRef": {"DataFrameRef": "2025-09-12", "DatedVehicleJourneyRef": "TEST:ServiceJourney:1"},
+					"VehicleLocation": {"Longitude": 10.5, "Latitude": 59.25},
+					"Bearing": 90,
+					"InCongestion": false
+				}
+			}]
+		}],
+		"EstimatedTimetableDelivery": [{
+			"EstimatedJourneyVersionFrame": [{
+				"EstimatedVehicleJourney": [{
+					"FramedVehicleJourneyRef": {"DatedVehicleJourneyRef": "T1"},
+					"EstimatedCalls": {"EstimatedCall": [
+						{"StopPointRef": "Q1", "Order": 1},
+						{"StopPointRef": "Q2", "Order": 2}
+					]}
+				}]
+			}]
+		}]
+	}}}`
+	sd, err := formatter.DecodeSIRIJSON(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sd.VehicleMonitoringDeliveries) != 1 || len(sd.VehicleMonitoringDeliveries[0].VehicleActivities) != 1 {
+		t.Fatal("expected one vehicle activity")
+	}
+	mvj := sd.VehicleMonitoringDeliveries[0].VehicleActivities[0].MonitoredVehicleJourney
+	if mvj == nil || mvj.LineRef == nil || *mvj.LineRef != "TEST:Line:1" {
+		t.Fatalf("unexpected journey: %+v", mvj)
+	}
+	if mvj.VehicleLocation.Latitude != 59.25 || mvj.VehicleLocation.Longitude != 10.5 {
+		t.Errorf("unexpected location: %+v", mvj.VehicleLocation)
+	}
+	if mvj.Bearing == nil || *mvj.Bearing != 90 || mvj.InCongestion == nil || *mvj.InCongestion {
+		t.Errorf("unexpected bearing/congestion")
+	}
+	calls := sd.EstimatedTimetableDeliveries[0].EstimatedJourneyVersionFrames[0].EstimatedVehicleJourneys[0].EstimatedCalls
+	if len(calls) != 2 || *calls[1].StopPointRef != "Q2" || *calls[1].Order != 2 {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+}
+
+func TestDecodeSIRIJSON_LowerCamelSingleObjects(t *testing.T) {
+	doc := `{"serviceDelivery": {
+		"situationExchangeDelivery": {
+			"situations": {"ptSituationElement": {
+				"situationNumber": "SX-1",
+				"summary": [{"value": "Спирката е закрита", "lang": "bg"}, {"value": "Stop closed", "lang": "en"}],
+				"description": "Works",
+				"infoLinks": {"infoLink": {"uri": "https://example.com", "label": "More"}}
+			}}
+		},
+		"vehicleMonitoringDelivery": {
+			"vehicleActivity": {
+				"monitoredVehicleJourney": {
+					"vehicleRef": "V1",
+					"vehicleLocation": {"srsName": "EPSG:3857", "coordinates": "2596597.3 5265003.5"}
+				}
+			}
+		}
+	}}`
+	sd, err := formatter.DecodeSIRIJSON(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sits := sd.SituationExchangeDeliveries[0].Situations
+	if len(sits) != 1 || *sits[0].SituationNumber != "SX-1" {
+		t.Fatalf("unexpected situations: %+v", sits)
+	}
+	if len(sits[0].Summaries) != 2 || sits[0].Summaries[0].Lang != "bg" || sits[0].Summaries[1].Value != "Stop closed" {
+		t.Errorf("unexpected summaries: %+v", sits[0].Summaries)
+	}
+	if len(sits[0].Descriptions) != 1 || sits[0].Descriptions[0].Value != "Works" {
+		t.Errorf("unexpected descriptions: %+v", sits[0].Descriptions)
+	}
+	if len(sits[0].InfoLinks) != 1 || sits[0].InfoLinks[0].Href != "https://example.com" {
+		t.Errorf("unexpected info links: %+v", sits[0].InfoLinks)
+	}
+
+	loc := sd.VehicleMonitoringDeliveries[0].VehicleActivities[0].MonitoredVehicleJourney.VehicleLocation
+	if math.Abs(loc.Longitude-23.325) > 0.001 || math.Abs(loc.Latitude-42.69) > 0.001 {
+		t.Errorf("projected location not resolved: %+v", loc)
+	}
+}
+
+func TestDecodeSIRIJSON_Invalid(t *testing.T) {
+	if _, err := formatter.DecodeSIRIJSON(strings.NewReader(`[1, 2]`)); err == nil {
+		t.Error("expected error for non-object document")
+	}
+	if _, err := formatter.DecodeSIRIJSON(strings.NewReader(`{"Siri":`)); err == nil {
+		t.Error("expected error for truncated document")
+	}
+}
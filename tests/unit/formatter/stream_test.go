@@ -0,0 +1,69 @@
+package formatter_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/converter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/formatter"
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+const streamDoc = `<Siri version="2.0" xmlns="http://www.siri.org.uk/siri"><ServiceDelivery>
+	<EstimatedTimetableDelivery><EstimatedJourneyVersionFrame>
+		<EstimatedVehicleJourney><FramedVehicleJourneyRef><DatedVehicleJourneyRef>T1</DatedVehicleJourneyRef></FramedVehicleJourneyRef></EstimatedVehicleJourney>
+		<EstimatedVehicleJourney><FramedVehicleJourneyRef><DatedVehicleJourneyRef>T2</DatedVehicleJourneyRef></FramedVehicleJourneyRef></EstimatedVehicleJourney>
+	</EstimatedJourneyVersionFrame></EstimatedTimetableDelivery>
+	<VehicleMonitoringDelivery><VehicleActivity><MonitoredVehicleJourney><VehicleRef>V1</VehicleRef></MonitoredVehicleJourney></VehicleActivity></VehicleMonitoringDelivery>
+	<SituationExchangeDelivery><Situations><PtSituationElement><SituationNumber>SX-1</SituationNumber></PtSituationElement></Situations></SituationExchangeDelivery>
+</ServiceDelivery></Siri>`
+
+func TestStreamSIRI(t *testing.T) {
+	var trips, vehicles, situations []string
+	err := formatter.StreamSIRI(strings.NewReader(streamDoc), formatter.Handler{
+		EstimatedVehicleJourney: func(evj *siri.EstimatedVehicleJourney) error {
+			if e := converter.MapETToTripUpdate(evj, converter.DefaultOptions()); e != nil {
+				trips = append(trips, e.ID)
+			}
+			return nil
+		},
+		VehicleActivity: func(va *siri.VehicleActivity) error {
+			vehicles = append(vehicles, *va.MonitoredVehicleJourney.VehicleRef)
+			return nil
+		},
+		PtSituationElement: func(sx *siri.PtSituationElement) error {
+			situations = append(situations, *sx.SituationNumber)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(trips, ",") != "T1,T2" || strings.Join(vehicles, ",") != "V1" || strings.Join(situations, ",") != "SX-1" {
+		t.Errorf("unexpected callbacks: trips=%v vehicles=%v situations=%v", trips, vehicles, situations)
+	}
+}
+
+func TestStreamSIRI_StopsOnHandlerError(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	err := formatter.StreamSIRI(strings.NewReader(streamDoc), formatter.Handler{
+		EstimatedVehicleJourney: func(*siri.EstimatedVehicleJourney) error {
+			calls++
+			return stop
+		},
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("err = %v after %d calls, want stop after 1", err, calls)
+	}
+}
+
+func TestStreamSIRI_MalformedXML(t *testing.T) {
+	err := formatter.StreamSIRI(strings.NewReader(`<Siri><ServiceDelivery><VehicleActivity>`), formatter.Handler{
+		VehicleActivity: func(*siri.VehicleActivity) error { return nil },
+	})
+	if err == nil {
+		t.Error("expected error for truncated document")
+	}
+}
@@ -0,0 +1,66 @@
+package siri_test
+
+import (
+	"encoding/xml"
+	"math"
+	"testing"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+func TestLocation_DecodeProjectedCoordinates(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		lat, lon float64
+	}{
+		{
+			name: "longitude latitude children",
+			xml:  `<VehicleLocation><Longitude>23.32</Longitude><Latitude>42.69</Latitude></VehicleLocation>`,
+			lat:  42.69, lon: 23.32,
+		},
+		{
+			name: "web mercator coordinates",
+			xml:  `<VehicleLocation srsName="EPSG:3857"><Coordinates>1197000 8374000</Coordinates></VehicleLocation>`,
+			lat:  59.8842, lon: 10.7528,
+		},
+		{
+			name: "utm 32n gml pos",
+			xml:  `<VehicleLocation srsName="urn:ogc:def:crs:EPSG::25832" xmlns:gml="http://www.opengis.net/gml/3.2"><gml:pos>597000 6643000</gml:pos></VehicleLocation>`,
+			lat:  59.9131, lon: 10.7346,
+		},
+		{
+			name: "sweref99 tm northing first",
+			xml:  `<VehicleLocation srsName="EPSG:3006"><Coordinates>6580822 674032</Coordinates></VehicleLocation>`,
+			lat:  59.3302, lon: 18.0592,
+		},
+		{
+			name: "swiss lv95",
+			xml:  `<VehicleLocation srsName="http://www.opengis.net/def/crs/EPSG/0/2056"><Coordinates>2700000,1100000</Coordinates></VehicleLocation>`,
+			lat:  46.0441, lon: 8.7305,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var loc siri.Location
+			if err := xml.Unmarshal([]byte(tt.xml), &loc); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if math.Abs(loc.Latitude-tt.lat) > 1e-3 || math.Abs(loc.Longitude-tt.lon) > 1e-3 {
+				t.Errorf("expected (%v, %v), got (%v, %v)", tt.lat, tt.lon, loc.Latitude, loc.Longitude)
+			}
+		})
+	}
+}
+
+func TestLocation_UnsupportedReferenceSystem(t *testing.T) {
+	var loc siri.Location
+	src := `<VehicleLocation srsName="EPSG:31467"><Coordinates>3500000 5400000</Coordinates></VehicleLocation>`
+	if err := xml.Unmarshal([]byte(src), &loc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if loc.Latitude != 0 || loc.Longitude != 0 {
+		t.Errorf("expected unresolved location, got (%v, %v)", loc.Latitude, loc.Longitude)
+	}
+}
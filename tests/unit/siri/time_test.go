@@ -0,0 +1,30 @@
+package siri_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/theoremus-urban-solutions/siri-to-gtfsrt/siri"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"PT5M", 5 * time.Minute, true},
+		{"-PT1M30S", -90 * time.Second, true},
+		{"P0DT1H", time.Hour, true},
+		{"PT0.5S", 500 * time.Millisecond, true},
+		{"P", 0, false},
+		{"PT", 0, false},
+		{"5 minutes", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := siri.ParseDuration(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}